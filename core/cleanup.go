@@ -0,0 +1,166 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+// minCleanupMargin keeps the restore ahead of the test deadline when DestroyMargin is small or zero.
+const minCleanupMargin = 5 * time.Second
+
+// CleanupMargin is the part of DestroyMargin left after the destroy commands for restoring the vars file,
+// a tenth of it with at least 5 seconds. The destroy commands of the terragrunt package are cancelled when it starts.
+func (r RunTime) CleanupMargin() time.Duration {
+	return max(r.DestroyMargin/10, minCleanupMargin)
+}
+
+// SetupVarsFile calls UpdateVarsFile and registers a t.Cleanup that restores the vars file
+// and, when clearCache is true, clears the cache folder.
+func SetupVarsFile(t *testing.T, cfg RunTime, fs FileSystem, clearCache bool) ([]byte, error) {
+	original, err := UpdateVarsFile(t, cfg, fs)
+	if err != nil {
+		return nil, err
+	}
+	registerVarsCleanup(t, cfg, fs, clearCache)
+
+	return original, nil
+}
+
+// SetupVarsLocals calls UpdateVarsLocals and registers the same cleanup as SetupVarsFile.
+func SetupVarsLocals(t *testing.T, cfg RunTime, fs FileSystem, patch LocalsPatch, clearCache bool) ([]byte, error) {
+	original, err := UpdateVarsLocals(t, cfg, fs, patch)
+	if err != nil {
+		return nil, err
+	}
+	registerVarsCleanup(t, cfg, fs, clearCache)
+
+	return original, nil
+}
+
+func registerVarsCleanup(t *testing.T, cfg RunTime, fs FileSystem, clearCache bool) {
+	var once sync.Once
+	var restoreErr error
+	restore := func() {
+		once.Do(func() {
+			restoreErr = RestoreVarsFile(t, cfg, fs)
+		})
+	}
+
+	// Restore ahead of the test deadline, the timeout panic skips t.Cleanup.
+	var timer *time.Timer
+	if deadline, ok := t.Deadline(); ok {
+		if wait := time.Until(deadline) - cfg.CleanupMargin(); wait > 0 {
+			timer = time.AfterFunc(wait, func() {
+				logger.Log(t, "Test deadline is close, restore "+cfg.VarsFile)
+				whenVarsFileFree(cfg, restore)
+			})
+		}
+	}
+
+	t.Cleanup(func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		restore()
+		if restoreErr != nil {
+			t.Errorf("failed to restore %s: %v", cfg.VarsFile, restoreErr)
+		}
+
+		if clearCache {
+			if err := clearCacheLocked(t, cfg, fs); err != nil {
+				t.Errorf("failed to clear cache folder: %v", err)
+			}
+		}
+	})
+}
+
+// clearCacheLocked clears the cache folder, holding the cache lock when locking is enabled.
+func clearCacheLocked(t *testing.T, cfg RunTime, fs FileSystem) error {
+	if !cfg.IsLocking {
+		return ClearFolder(t, cfg, fs)
+	}
+
+	lock, err := AcquireLock(t, cfg, CacheLockPath(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to lock terragrunt cache: %w", err)
+	}
+	clearErr := ClearFolder(t, cfg, fs)
+	if err := lock.Release(); err != nil {
+		logger.Log(t, "Failed to release terragrunt cache lock:", err)
+	}
+
+	return clearErr
+}
+
+// varsUse counts the helpers running with a vars file and holds a restore deferred until they are done.
+type varsUse struct {
+	users   int
+	pending []func()
+}
+
+var (
+	varsUsesMu sync.Mutex
+	varsUses   = map[string]*varsUse{}
+)
+
+func varsUseKey(cfg RunTime) string {
+	path := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+
+	return path
+}
+
+// UseVarsFile marks the vars file as read by a running command until the returned function is called.
+// The restore ahead of the test deadline waits for it, so a running apply or destroy keeps its content.
+func UseVarsFile(cfg RunTime) func() {
+	key := varsUseKey(cfg)
+
+	varsUsesMu.Lock()
+	use, ok := varsUses[key]
+	if !ok {
+		use = &varsUse{}
+		varsUses[key] = use
+	}
+	use.users++
+	varsUsesMu.Unlock()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			varsUsesMu.Lock()
+			use.users--
+			var pending []func()
+			if use.users == 0 {
+				pending = use.pending
+				delete(varsUses, key)
+			}
+			varsUsesMu.Unlock()
+
+			for _, fn := range pending {
+				fn()
+			}
+		})
+	}
+}
+
+// whenVarsFileFree runs fn now, or once the last user of the vars file is done.
+func whenVarsFileFree(cfg RunTime, fn func()) {
+	varsUsesMu.Lock()
+	if use, ok := varsUses[varsUseKey(cfg)]; ok {
+		use.pending = append(use.pending, fn)
+		varsUsesMu.Unlock()
+
+		return
+	}
+	varsUsesMu.Unlock()
+
+	fn()
+}
@@ -0,0 +1,95 @@
+package core_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockSetupVarsFile(t *testing.T) {
+	t.Parallel()
+	// Create a mock file system
+	mockFS := new(MockFileSystem)
+
+	// Define the configuration
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: "test/setup-vars",
+			TgDownloadDir: "test/setup-download-dir",
+		},
+		Content: "new content",
+	}
+
+	rootVarsPath := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
+	originalContent := []byte("original content")
+
+	// Update expectations
+	mockFS.On("ReadFile", rootVarsPath).Return(originalContent, nil)
+	mockFS.On("Stat", rootVarsPath).Return(MockFileInfo{name: cfg.VarsFile, mode: 0644}, nil)
+	mockFS.On("WriteFile", rootVarsPath, []byte(cfg.Content), fs.FileMode(0644)).Return(nil).Once()
+
+	// Cleanup expectations
+	mockFS.On("WriteFile", rootVarsPath, originalContent, fs.FileMode(0644)).Return(nil).Once()
+	mockFS.On("ReadDir", cfg.Paths.TgDownloadDir).Return([]os.DirEntry{
+		MockDirEntry{name: "folder1", isDir: true},
+		MockDirEntry{name: ".plugins", isDir: true},
+	}, nil)
+	mockFS.On("RemoveAll", filepath.Join(cfg.Paths.TgDownloadDir, "folder1")).Return(nil)
+
+	// Run in a subtest so the registered cleanup runs before the assertions
+	t.Run("setup", func(t *testing.T) {
+		_, err := core.SetupVarsFile(t, cfg, mockFS, true)
+		require.NoError(t, err)
+
+		// Nothing is restored before the test finishes
+		mockFS.AssertNumberOfCalls(t, "WriteFile", 1)
+	})
+
+	// Assert that all expectations were met
+	mockFS.AssertExpectations(t)
+}
+
+func TestMockSetupVarsFile_DeadlineWaitsForUse(t *testing.T) {
+	t.Parallel()
+	deadline, ok := t.Deadline()
+	if !ok {
+		t.Skip("test runs without a deadline")
+	}
+
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: t.TempDir(),
+		},
+		Content: "new content",
+		// Leaves the restore timer 200ms before it fires.
+		DestroyMargin: (time.Until(deadline) - 200*time.Millisecond) * 10,
+	}
+	rootVarsPath := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
+	require.NoError(t, os.WriteFile(rootVarsPath, []byte("original content"), 0644))
+
+	t.Run("setup", func(t *testing.T) {
+		release := core.UseVarsFile(cfg)
+		_, err := core.SetupVarsFile(t, cfg, core.OsFileSystem{}, false)
+		require.NoError(t, err)
+
+		// The timer fires while the vars file is in use and leaves it alone
+		time.Sleep(500 * time.Millisecond)
+		content, err := os.ReadFile(rootVarsPath)
+		require.NoError(t, err)
+		assert.Equal(t, "new content", string(content))
+
+		// The deferred restore runs once the last user is done
+		release()
+		content, err = os.ReadFile(rootVarsPath)
+		require.NoError(t, err)
+		assert.Equal(t, "original content", string(content))
+	})
+}
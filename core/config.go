@@ -1,13 +1,20 @@
 package core
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GoGstickGo/terratest-helpers/pkg/parameters"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"gopkg.in/yaml.v3"
 )
 
 type FolderPaths struct {
@@ -17,83 +24,621 @@ type FolderPaths struct {
 	TerragruntDir string
 }
 
+// RetryPolicy retries apply and destroy when their output matches a retryable error.
+type RetryPolicy struct {
+	// MaxAttempts counts the first run, zero or one disables retries.
+	MaxAttempts int
+	// Backoff is the wait before the first retry, it doubles for every further attempt.
+	Backoff time.Duration
+	// RetryableErrors maps a regular expression to the reason logged on a retry.
+	// They are merged with terratest's defaults and the AWS eventual consistency errors, these win on conflicts.
+	RetryableErrors map[string]string
+}
+
+// AWSSettings override the account and region the AWS helpers read from the vars file.
+type AWSSettings struct {
+	Region    string
+	AccountID string
+	// Profile is the shared config profile, the aws_profile or stage local of the vars file is used otherwise.
+	Profile string
+	// AssumeRole is a role ARN, or a role name in the target account, assumed by the AWS helpers.
+	AssumeRole string
+	// VPCID is the VPC Destroy removes leftover ENIs from, the vpc_id local of the vars file is used otherwise.
+	VPCID string
+}
+
 type RunTime struct {
-	Paths         FolderPaths
-	Content       string
-	VarsFile      string
-	IsPluginCache bool
-	IsDebug       bool
-	Pause         time.Duration
+	Paths             FolderPaths
+	Content           string
+	VarsFile          string
+	IsPluginCache     bool
+	IsDebug           bool
+	Pause             time.Duration
+	MaxPause          time.Duration
+	PauseMode         string
+	PauseFile         string
+	IsLocking         bool
+	LockTimeout       time.Duration
+	LockRetryInterval time.Duration
+	// DestroyMargin is kept free before the test deadline so Destroy can still run after a timed out command.
+	DestroyMargin time.Duration
+	// InitRetries is how often init is re-run after a recognized plugin cache error.
+	InitRetries int
+	// InitBackoff is the wait before the first re-init, it doubles for every further attempt.
+	InitBackoff time.Duration
+	// ClearPluginDir also empties TfPluginDir before a re-init, the providers are downloaded again.
+	ClearPluginDir bool
+	Retry          RetryPolicy
+	// RunID makes resource names unique per test run, see UniqueName. It defaults to one ID per test binary,
+	// ForTest scopes it to a single test.
+	RunID string
+	AWS   AWSSettings
+	// AllowedAccountIDs enables the account guard, the AWS helpers refuse to run in any other account.
+	AllowedAccountIDs []string
+}
+
+// Option customizes a RunTime after the config file and environment variables have been applied.
+type Option func(*RunTime)
+
+func WithTerragruntDir(dir string) Option {
+	return func(r *RunTime) { r.Paths.TerragruntDir = dir }
+}
+
+func WithHomeDir(dir string) Option {
+	return func(r *RunTime) { r.Paths.HomeDir = dir }
+}
+
+func WithDownloadDir(dir string) Option {
+	return func(r *RunTime) { r.Paths.TgDownloadDir = dir }
+}
+
+func WithPluginDir(dir string) Option {
+	return func(r *RunTime) { r.Paths.TfPluginDir = dir }
+}
+
+func WithContent(content string) Option {
+	return func(r *RunTime) { r.Content = content }
+}
+
+func WithVarsFile(file string) Option {
+	return func(r *RunTime) { r.VarsFile = file }
+}
+
+func WithPluginCache(enabled bool) Option {
+	return func(r *RunTime) { r.IsPluginCache = enabled }
+}
+
+func WithDebug(enabled bool) Option {
+	return func(r *RunTime) { r.IsDebug = enabled }
+}
+
+func WithPause(pause time.Duration) Option {
+	return func(r *RunTime) { r.Pause = pause }
+}
+
+// WithMaxPause caps Pause, zero means no limit.
+func WithMaxPause(limit time.Duration) Option {
+	return func(r *RunTime) { r.MaxPause = limit }
+}
+
+// WithPauseMode sets how PauseTest waits, see testutils.PauseTest for the supported modes.
+func WithPauseMode(mode string) Option {
+	return func(r *RunTime) { r.PauseMode = mode }
+}
+
+func WithPauseFile(path string) Option {
+	return func(r *RunTime) { r.PauseFile = path }
+}
+
+func WithLocking(enabled bool) Option {
+	return func(r *RunTime) { r.IsLocking = enabled }
+}
+
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(r *RunTime) { r.LockTimeout = timeout }
+}
+
+func WithLockRetryInterval(interval time.Duration) Option {
+	return func(r *RunTime) { r.LockRetryInterval = interval }
+}
+
+func WithDestroyMargin(margin time.Duration) Option {
+	return func(r *RunTime) { r.DestroyMargin = margin }
+}
+
+func WithInitRetries(retries int) Option {
+	return func(r *RunTime) { r.InitRetries = retries }
+}
+
+func WithInitBackoff(backoff time.Duration) Option {
+	return func(r *RunTime) { r.InitBackoff = backoff }
 }
 
-// NewFolderConfig creates a new instance of FolderConfig with default values.
-func NewConfig() RunTime {
+func WithClearPluginDir(enabled bool) Option {
+	return func(r *RunTime) { r.ClearPluginDir = enabled }
+}
+
+func WithRetryMaxAttempts(attempts int) Option {
+	return func(r *RunTime) { r.Retry.MaxAttempts = attempts }
+}
 
-	terragruntDir := getEnvVar("TT_TERRAGRUNT_ROOT_DIR", "../../")
-	homeDir := getEnvVar("TT_HOME_DIR", "")
-	tgDownloadDir := getEnvVar("TT_TERRAGRUNT_DOWNLOAD_DIR", "")
-	tfPluginDir := getEnvVar("TT_TERRAGRUNT_PLUGIN_DIR", "")
-	content := getEnvVar("TT_CONTENT", parameters.TGRootVars)
-	varsFile := getEnvVar("TT_VARS_FILE", "root_vars.hcl")
-	isPluginCache := getEnvVarBool("TT_PLUGIN_CACHE", false)
-	isDebug := getEnvVarBool("TT_DEBUG", false)
-	pause := getEnvVarDuration("TT_PAUSE", 0)
+func WithRetryBackoff(backoff time.Duration) Option {
+	return func(r *RunTime) { r.Retry.Backoff = backoff }
+}
+
+// WithRunID overrides the generated run ID, for example to rerun against the resources of a failed run.
+func WithRunID(id string) Option {
+	return func(r *RunTime) { r.RunID = id }
+}
 
-	// Set default values
-	if homeDir == "" {
-		homeDir, _ = os.UserHomeDir()
+func WithAWSRegion(region string) Option {
+	return func(r *RunTime) { r.AWS.Region = region }
+}
+
+func WithAWSAccountID(accountID string) Option {
+	return func(r *RunTime) { r.AWS.AccountID = accountID }
+}
+
+func WithAWSProfile(profile string) Option {
+	return func(r *RunTime) { r.AWS.Profile = profile }
+}
+
+// WithAssumeRole makes the AWS helpers assume role, an ARN or a role name in the target account.
+func WithAssumeRole(role string) Option {
+	return func(r *RunTime) { r.AWS.AssumeRole = role }
+}
+
+// WithVPCID sets the VPC Destroy removes leftover ENIs from.
+func WithVPCID(id string) Option {
+	return func(r *RunTime) { r.AWS.VPCID = id }
+}
+
+func WithAllowedAccountIDs(ids ...string) Option {
+	return func(r *RunTime) { r.AllowedAccountIDs = ids }
+}
+
+// WithRetryableError retries apply and destroy when their output matches pattern.
+func WithRetryableError(pattern, reason string) Option {
+	return func(r *RunTime) {
+		if r.Retry.RetryableErrors == nil {
+			r.Retry.RetryableErrors = map[string]string{}
+		}
+		r.Retry.RetryableErrors[pattern] = reason
 	}
+}
 
-	if tgDownloadDir == "" {
-		tgDownloadDir = filepath.Join(homeDir, ".terragrunt-cache")
+var accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+
+// fileConfig is the layout of a YAML, JSON or HCL config file, durations use the format of parseDuration.
+type fileConfig struct {
+	TerragruntDir     *string `json:"terragrunt_dir" yaml:"terragrunt_dir" hcl:"terragrunt_dir,optional"`
+	HomeDir           *string `json:"home_dir" yaml:"home_dir" hcl:"home_dir,optional"`
+	DownloadDir       *string `json:"download_dir" yaml:"download_dir" hcl:"download_dir,optional"`
+	PluginDir         *string `json:"plugin_dir" yaml:"plugin_dir" hcl:"plugin_dir,optional"`
+	Content           *string `json:"content" yaml:"content" hcl:"content,optional"`
+	VarsFile          *string `json:"vars_file" yaml:"vars_file" hcl:"vars_file,optional"`
+	PluginCache       *bool   `json:"plugin_cache" yaml:"plugin_cache" hcl:"plugin_cache,optional"`
+	Debug             *bool   `json:"debug" yaml:"debug" hcl:"debug,optional"`
+	Pause             *string `json:"pause" yaml:"pause" hcl:"pause,optional"`
+	MaxPause          *string `json:"max_pause" yaml:"max_pause" hcl:"max_pause,optional"`
+	PauseMode         *string `json:"pause_mode" yaml:"pause_mode" hcl:"pause_mode,optional"`
+	PauseFile         *string `json:"pause_file" yaml:"pause_file" hcl:"pause_file,optional"`
+	Lock              *bool   `json:"lock" yaml:"lock" hcl:"lock,optional"`
+	LockTimeout       *string `json:"lock_timeout" yaml:"lock_timeout" hcl:"lock_timeout,optional"`
+	LockRetryInterval *string `json:"lock_retry_interval" yaml:"lock_retry_interval" hcl:"lock_retry_interval,optional"`
+	DestroyMargin     *string `json:"destroy_margin" yaml:"destroy_margin" hcl:"destroy_margin,optional"`
+	InitRetries       *int    `json:"init_retries" yaml:"init_retries" hcl:"init_retries,optional"`
+	InitBackoff       *string `json:"init_backoff" yaml:"init_backoff" hcl:"init_backoff,optional"`
+	ClearPluginDir    *bool   `json:"clear_plugin_dir" yaml:"clear_plugin_dir" hcl:"clear_plugin_dir,optional"`
+	RetryMaxAttempts  *int    `json:"retry_max_attempts" yaml:"retry_max_attempts" hcl:"retry_max_attempts,optional"`
+	RetryBackoff      *string `json:"retry_backoff" yaml:"retry_backoff" hcl:"retry_backoff,optional"`
+	// RetryableErrors maps a regular expression to the reason logged on a retry.
+	RetryableErrors map[string]string `json:"retryable_errors" yaml:"retryable_errors" hcl:"retryable_errors,optional"`
+	AWSRegion       *string           `json:"aws_region" yaml:"aws_region" hcl:"aws_region,optional"`
+	AWSAccountID    *string           `json:"aws_account_id" yaml:"aws_account_id" hcl:"aws_account_id,optional"`
+	AWSProfile      *string           `json:"aws_profile" yaml:"aws_profile" hcl:"aws_profile,optional"`
+	AssumeRole      *string           `json:"assume_role" yaml:"assume_role" hcl:"assume_role,optional"`
+	VPCID           *string           `json:"vpc_id" yaml:"vpc_id" hcl:"vpc_id,optional"`
+	AllowedAccounts []string          `json:"allowed_account_ids" yaml:"allowed_account_ids" hcl:"allowed_account_ids,optional"`
+}
+
+// NewConfig creates a new RunTime from the TT_* environment variables and the given options.
+// Invalid values are ignored and the defaults are kept, use LoadConfig to get them reported.
+func NewConfig(opts ...Option) RunTime {
+	cfg, _ := LoadConfig("", opts...)
+
+	return cfg
+}
+
+// LoadConfig creates a RunTime from defaults, overlaid by the config file at path, the TT_* environment
+// variables and finally opts. An empty path falls back to TT_CONFIG_FILE, no file is read if both are empty.
+// The returned error lists every invalid value, the RunTime is still usable with defaults in their place.
+func LoadConfig(path string, opts ...Option) (RunTime, error) {
+	cfg := defaultConfig()
+
+	var errs []error
+
+	if path == "" {
+		path = os.Getenv("TT_CONFIG_FILE")
+	}
+	if path != "" {
+		errs = append(errs, applyFile(&cfg, path)...)
+	}
+
+	errs = append(errs, applyEnv(&cfg)...)
+
+	for _, opt := range opts {
+		opt(&cfg)
 	}
 
-	if tfPluginDir == "" {
-		tfPluginDir = filepath.Join(tgDownloadDir, ".plugins")
+	setDefaultPaths(&cfg)
+
+	// Options are validated like the file and the environment, invalid values fall back to the defaults.
+	errs = append(errs, validateConfig(&cfg)...)
+
+	if len(errs) > 0 {
+		return cfg, fmt.Errorf("%w:\n%w", ErrInvalidConfig, errors.Join(errs...))
 	}
 
+	return cfg, nil
+}
+
+func defaultConfig() RunTime {
 	return RunTime{
 		Paths: FolderPaths{
-			TerragruntDir: terragruntDir,
-			HomeDir:       homeDir,
-			TgDownloadDir: tgDownloadDir,
-			TfPluginDir:   tfPluginDir,
+			TerragruntDir: "../../",
+		},
+		Content:           parameters.TGRootVars,
+		VarsFile:          "root_vars.hcl",
+		LockTimeout:       15 * time.Minute,
+		LockRetryInterval: defaultLockRetryInterval,
+		DestroyMargin:     5 * time.Minute,
+		InitRetries:       1,
+		InitBackoff:       10 * time.Second,
+		Retry: RetryPolicy{
+			// Terratest retries its own errors already, these retries are opt-in.
+			MaxAttempts: 1,
+			Backoff:     15 * time.Second,
 		},
-		VarsFile:      varsFile,
-		IsDebug:       isDebug,
-		Content:       content,
-		IsPluginCache: isPluginCache,
-		Pause:         pause,
 	}
 }
 
-func getEnvVar(key string, defaultValue string) string {
+// setDefaultPaths derives the paths left empty from the home directory.
+func setDefaultPaths(cfg *RunTime) {
+	if cfg.Paths.HomeDir == "" {
+		cfg.Paths.HomeDir, _ = os.UserHomeDir()
+	}
+
+	if cfg.Paths.TgDownloadDir == "" {
+		cfg.Paths.TgDownloadDir = filepath.Join(cfg.Paths.HomeDir, ".terragrunt-cache")
+	}
+
+	if cfg.Paths.TfPluginDir == "" {
+		cfg.Paths.TfPluginDir = filepath.Join(cfg.Paths.TgDownloadDir, ".plugins")
+	}
+}
+
+// validateConfig checks the merged configuration and resets invalid values to their defaults.
+func validateConfig(cfg *RunTime) []error {
+	var errs []error
+
+	if cfg.RunID == "" {
+		cfg.RunID = processRunID()
+	} else if err := validateRunID(cfg.RunID); err != nil {
+		errs = append(errs, err)
+		cfg.RunID = processRunID()
+	}
+
+	for _, id := range cfg.AllowedAccountIDs {
+		if !accountIDPattern.MatchString(id) {
+			errs = append(errs, fmt.Errorf("allowed account ID %q: must be 12 digits", id))
+		}
+	}
+
+	// Refuse pauses beyond the cap, CI should not idle for hours.
+	if cfg.MaxPause > 0 && cfg.Pause > cfg.MaxPause {
+		errs = append(errs, fmt.Errorf("%w: %s is longer than %s", ErrPauseTooLong, cfg.Pause, cfg.MaxPause))
+		cfg.Pause = cfg.MaxPause
+	}
+
+	return append(errs, validateRetries(cfg)...)
+}
+
+// validateRetries checks the init and apply/destroy retry settings.
+func validateRetries(cfg *RunTime) []error {
+	var errs []error
+	defaults := defaultConfig()
+
+	if cfg.InitRetries < 0 {
+		errs = append(errs, fmt.Errorf("init retries: must not be negative, got %d", cfg.InitRetries))
+		cfg.InitRetries = defaults.InitRetries
+	}
+
+	if cfg.Retry.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("retry max attempts: must be at least 1, got %d", cfg.Retry.MaxAttempts))
+		cfg.Retry.MaxAttempts = defaults.Retry.MaxAttempts
+	}
+
+	for pattern := range cfg.Retry.RetryableErrors {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("retryable error %q: %w", pattern, err))
+		}
+	}
+
+	return errs
+}
+
+// applyFile decodes the config file based on its extension and overlays it on cfg.
+func applyFile(cfg *RunTime, path string) []error {
+	fc, err := decodeFile(path)
+	if err != nil {
+		return []error{err}
+	}
+
+	setter := fileSetter{path: path}
+
+	setIfPresent(fc.TerragruntDir, &cfg.Paths.TerragruntDir)
+	setIfPresent(fc.HomeDir, &cfg.Paths.HomeDir)
+	setIfPresent(fc.DownloadDir, &cfg.Paths.TgDownloadDir)
+	setIfPresent(fc.PluginDir, &cfg.Paths.TfPluginDir)
+	setIfPresent(fc.Content, &cfg.Content)
+	setIfPresent(fc.VarsFile, &cfg.VarsFile)
+	setIfPresent(fc.PluginCache, &cfg.IsPluginCache)
+	setIfPresent(fc.Debug, &cfg.IsDebug)
+	setter.duration("pause", fc.Pause, &cfg.Pause)
+	setter.duration("max_pause", fc.MaxPause, &cfg.MaxPause)
+	setIfPresent(fc.PauseMode, &cfg.PauseMode)
+	setIfPresent(fc.PauseFile, &cfg.PauseFile)
+	setIfPresent(fc.Lock, &cfg.IsLocking)
+	setter.duration("lock_timeout", fc.LockTimeout, &cfg.LockTimeout)
+	setter.duration("lock_retry_interval", fc.LockRetryInterval, &cfg.LockRetryInterval)
+	setter.duration("destroy_margin", fc.DestroyMargin, &cfg.DestroyMargin)
+	setter.duration("init_backoff", fc.InitBackoff, &cfg.InitBackoff)
+	setIfPresent(fc.ClearPluginDir, &cfg.ClearPluginDir)
+	setter.duration("retry_backoff", fc.RetryBackoff, &cfg.Retry.Backoff)
+
+	applyFileAWS(cfg, fc)
+	setter.retries(cfg, fc)
+
+	return setter.errs
+}
+
+// decodeFile reads the config file at path, unknown keys are an error in every format.
+func decodeFile(path string) (fileConfig, error) {
+	var fc fileConfig
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fc, fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&fc); err != nil {
+			return fc, fmt.Errorf("failed to decode config file %s: %w", path, err)
+		}
+	case ".json":
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fc, fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&fc); err != nil {
+			return fc, fmt.Errorf("failed to decode config file %s: %w", path, err)
+		}
+	case ".hcl":
+		if err := hclsimple.DecodeFile(path, nil, &fc); err != nil {
+			return fc, fmt.Errorf("failed to decode config file %s: %w", path, err)
+		}
+	default:
+		return fc, fmt.Errorf("%w: %q", ErrUnsupportedConfigFormat, ext)
+	}
+
+	return fc, nil
+}
+
+func applyFileAWS(cfg *RunTime, fc fileConfig) {
+	setIfPresent(fc.AWSRegion, &cfg.AWS.Region)
+	setIfPresent(fc.AWSAccountID, &cfg.AWS.AccountID)
+	setIfPresent(fc.AWSProfile, &cfg.AWS.Profile)
+	setIfPresent(fc.AssumeRole, &cfg.AWS.AssumeRole)
+	setIfPresent(fc.VPCID, &cfg.AWS.VPCID)
+	if fc.AllowedAccounts != nil {
+		cfg.AllowedAccountIDs = fc.AllowedAccounts
+	}
+}
+
+func setIfPresent[T any](value *T, target *T) {
+	if value != nil {
+		*target = *value
+	}
+}
+
+// fileSetter collects the invalid values of the config file at path.
+type fileSetter struct {
+	path string
+	errs []error
+}
+
+func (s *fileSetter) duration(key string, value *string, target *time.Duration) {
+	if value == nil {
+		return
+	}
+	d, err := parseDuration(*value)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s: %s: %w", s.path, key, err))
+
+		return
+	}
+	*target = d
+}
+
+func (s *fileSetter) retries(cfg *RunTime, fc fileConfig) {
+	if fc.InitRetries != nil {
+		if *fc.InitRetries < 0 {
+			s.errs = append(s.errs, fmt.Errorf("init_retries: must not be negative, got %d", *fc.InitRetries))
+		} else {
+			cfg.InitRetries = *fc.InitRetries
+		}
+	}
+	if fc.RetryMaxAttempts != nil {
+		if *fc.RetryMaxAttempts < 1 {
+			s.errs = append(s.errs, fmt.Errorf("retry_max_attempts: must be at least 1, got %d", *fc.RetryMaxAttempts))
+		} else {
+			cfg.Retry.MaxAttempts = *fc.RetryMaxAttempts
+		}
+	}
+	if len(fc.RetryableErrors) > 0 {
+		cfg.Retry.RetryableErrors = fc.RetryableErrors
+	}
+}
+
+// applyEnv overlays the TT_* environment variables on cfg.
+func applyEnv(cfg *RunTime) []error {
+	var errs []error
+
+	setEnvVar("TT_TERRAGRUNT_ROOT_DIR", &cfg.Paths.TerragruntDir)
+	setEnvVar("TT_HOME_DIR", &cfg.Paths.HomeDir)
+	setEnvVar("TT_TERRAGRUNT_DOWNLOAD_DIR", &cfg.Paths.TgDownloadDir)
+	setEnvVar("TT_TERRAGRUNT_PLUGIN_DIR", &cfg.Paths.TfPluginDir)
+	setEnvVar("TT_CONTENT", &cfg.Content)
+	setEnvVar("TT_VARS_FILE", &cfg.VarsFile)
+	errs = appendErr(errs, setEnvVarBool("TT_PLUGIN_CACHE", &cfg.IsPluginCache))
+	errs = appendErr(errs, setEnvVarBool("TT_DEBUG", &cfg.IsDebug))
+	errs = appendErr(errs, setEnvVarDuration("TT_PAUSE", &cfg.Pause))
+	errs = appendErr(errs, setEnvVarDuration("TT_PAUSE_MAX", &cfg.MaxPause))
+	setEnvVar("TT_PAUSE_MODE", &cfg.PauseMode)
+	setEnvVar("TT_PAUSE_FILE", &cfg.PauseFile)
+	errs = appendErr(errs, setEnvVarBool("TT_LOCK", &cfg.IsLocking))
+	errs = appendErr(errs, setEnvVarDuration("TT_LOCK_TIMEOUT", &cfg.LockTimeout))
+	errs = appendErr(errs, setEnvVarDuration("TT_LOCK_RETRY_INTERVAL", &cfg.LockRetryInterval))
+	errs = appendErr(errs, setEnvVarDuration("TT_DESTROY_MARGIN", &cfg.DestroyMargin))
+	errs = appendErr(errs, setEnvVarCount("TT_INIT_RETRIES", &cfg.InitRetries))
+	errs = appendErr(errs, setEnvVarDuration("TT_INIT_BACKOFF", &cfg.InitBackoff))
+	errs = appendErr(errs, setEnvVarBool("TT_CLEAR_PLUGIN_DIR", &cfg.ClearPluginDir))
+	errs = appendErr(errs, setEnvVarAttempts("TT_RETRY_MAX_ATTEMPTS", &cfg.Retry.MaxAttempts))
+	errs = appendErr(errs, setEnvVarDuration("TT_RETRY_BACKOFF", &cfg.Retry.Backoff))
+	setEnvVar("TT_RUN_ID", &cfg.RunID)
+	setEnvVar("TT_AWS_REGION", &cfg.AWS.Region)
+	setEnvVar("TT_AWS_ACCOUNT_ID", &cfg.AWS.AccountID)
+	setEnvVar("TT_AWS_PROFILE", &cfg.AWS.Profile)
+	setEnvVar("TT_ASSUME_ROLE", &cfg.AWS.AssumeRole)
+	setEnvVar("TT_VPC_ID", &cfg.AWS.VPCID)
+	if value := os.Getenv("TT_ALLOWED_ACCOUNT_IDS"); value != "" {
+		cfg.AllowedAccountIDs = nil
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				cfg.AllowedAccountIDs = append(cfg.AllowedAccountIDs, id)
+			}
+		}
+	}
+
+	return errs
+}
+
+func appendErr(errs []error, err error) []error {
+	if err != nil {
+		return append(errs, err)
+	}
+
+	return errs
+}
+
+func setEnvVar(key string, target *string) {
+	if value := os.Getenv(key); value != "" {
+		*target = value
+	}
+}
+
+func setEnvVarBool(key string, target *bool) error {
 	value := os.Getenv(key)
 	if value == "" {
-		return defaultValue
+		return nil
 	}
+	temp, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s: invalid boolean %q", key, value)
+	}
+	*target = temp
+
+	return nil
+}
+
+// setEnvVarCount reads a non-negative integer.
+func setEnvVarCount(key string, target *int) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	temp, err := strconv.Atoi(value)
+	if err != nil || temp < 0 {
+		return fmt.Errorf("%s: invalid count %q", key, value)
+	}
+	*target = temp
 
-	return value
+	return nil
 }
 
-func getEnvVarBool(key string, defaultValue bool) bool {
+// setEnvVarAttempts reads a positive integer, the first run counts as an attempt.
+func setEnvVarAttempts(key string, target *int) error {
 	value := os.Getenv(key)
 	if value == "" {
-		return defaultValue
+		return nil
 	}
-	temp, _ := strconv.ParseBool(value)
+	temp, err := strconv.Atoi(value)
+	if err != nil || temp < 1 {
+		return fmt.Errorf("%s: invalid attempts %q, must be at least 1", key, value)
+	}
+	*target = temp
 
-	return temp
+	return nil
 }
 
-func getEnvVarDuration(key string, defaultValue time.Duration) time.Duration {
+func setEnvVarDuration(key string, target *time.Duration) error {
 	value := os.Getenv(key)
 	if value == "" {
-		return defaultValue
+		return nil
+	}
+	temp, err := parseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
 	}
-	tempInt, _ := strconv.Atoi(value)
+	*target = temp
 
-	return time.Duration(tempInt) * time.Minute
+	return nil
 }
 
-var ErrFailedToReadDirectory = errors.New("failed to read directory")
+// parseDuration accepts Go duration strings like "45s" or "1m30s".
+// A bare integer is a number of minutes for compatibility with the original TT_PAUSE format.
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	if minutes, err := strconv.Atoi(value); err == nil {
+		if minutes < 0 {
+			return 0, fmt.Errorf("%w %q: must not be negative", ErrInvalidDuration, value)
+		}
+
+		return time.Duration(minutes) * time.Minute, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q: use minutes or a duration like 90s", ErrInvalidDuration, value)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%w %q: must not be negative", ErrInvalidDuration, value)
+	}
+
+	return d, nil
+}
+
+var (
+	ErrFailedToReadDirectory   = errors.New("failed to read directory")
+	ErrInvalidConfig           = errors.New("invalid configuration")
+	ErrUnsupportedConfigFormat = errors.New("unsupported config file format")
+	ErrInvalidDuration         = errors.New("invalid duration")
+	ErrPauseTooLong            = errors.New("pause exceeds the maximum")
+	ErrInvalidRunID            = errors.New("invalid run ID")
+)
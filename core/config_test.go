@@ -0,0 +1,296 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	return path
+}
+
+func TestMockLoadConfig_Files(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"config.yaml": `
+terragrunt_dir: ../../example
+download_dir: /tmp/tg-cache
+plugin_cache: true
+pause: 90s
+`,
+		"config.json": `{
+  "terragrunt_dir": "../../example",
+  "download_dir": "/tmp/tg-cache",
+  "plugin_cache": true,
+  "pause": "90s"
+}`,
+		"config.hcl": `
+terragrunt_dir = "../../example"
+download_dir   = "/tmp/tg-cache"
+plugin_cache   = true
+pause          = "90s"
+`,
+	}
+
+	for name, content := range files {
+		path := writeConfigFile(t, name, content)
+
+		cfg, err := core.LoadConfig(path, core.WithHomeDir("/home/tester"))
+		require.NoError(t, err, name)
+
+		assert.Equal(t, "../../example", cfg.Paths.TerragruntDir, name)
+		assert.Equal(t, "/tmp/tg-cache", cfg.Paths.TgDownloadDir, name)
+		assert.Equal(t, "/tmp/tg-cache/.plugins", cfg.Paths.TfPluginDir, name)
+		assert.True(t, cfg.IsPluginCache, name)
+		assert.Equal(t, 90*time.Second, cfg.Pause, name)
+		assert.Equal(t, "root_vars.hcl", cfg.VarsFile, name)
+	}
+}
+
+func TestMockLoadConfig_Options(t *testing.T) {
+	t.Parallel()
+
+	path := writeConfigFile(t, "config.yaml", "terragrunt_dir: ../from-file\ndebug: true\n")
+
+	cfg, err := core.LoadConfig(path,
+		core.WithTerragruntDir("../from-option"),
+		core.WithHomeDir("/home/tester"),
+		core.WithPause(time.Minute),
+	)
+	require.NoError(t, err)
+
+	// Options win over the file
+	assert.Equal(t, "../from-option", cfg.Paths.TerragruntDir)
+	assert.True(t, cfg.IsDebug)
+	assert.Equal(t, time.Minute, cfg.Pause)
+	assert.Equal(t, "/home/tester/.terragrunt-cache", cfg.Paths.TgDownloadDir)
+}
+
+func TestMockLoadConfig_InvalidFile(t *testing.T) {
+	t.Parallel()
+
+	path := writeConfigFile(t, "config.yaml", "pause: soon\nlock_timeout: later\n")
+
+	_, err := core.LoadConfig(path)
+	require.ErrorIs(t, err, core.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), `pause: invalid duration "soon"`)
+	assert.Contains(t, err.Error(), `lock_timeout: invalid duration "later"`)
+	require.ErrorIs(t, err, core.ErrInvalidDuration)
+
+	path = writeConfigFile(t, "config.yaml", "terragrunt_directory: ../typo\n")
+	_, err = core.LoadConfig(path)
+	require.ErrorIs(t, err, core.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), "terragrunt_directory")
+
+	_, err = core.LoadConfig(writeConfigFile(t, "config.toml", ""))
+	require.ErrorIs(t, err, core.ErrUnsupportedConfigFormat)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockLoadConfig_Env(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "terragrunt_dir: ../from-file\nvars_file: file_vars.hcl\n")
+
+	t.Setenv("TT_CONFIG_FILE", path)
+	t.Setenv("TT_TERRAGRUNT_ROOT_DIR", "../from-env")
+	t.Setenv("TT_DEBUG", "yes")
+	t.Setenv("TT_PLUGIN_CACHE", "true")
+	t.Setenv("TT_PAUSE", "later")
+
+	cfg, err := core.LoadConfig("")
+
+	// Every invalid value is reported
+	require.ErrorIs(t, err, core.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), `TT_DEBUG: invalid boolean "yes"`)
+	assert.Contains(t, err.Error(), `TT_PAUSE`)
+
+	// Environment variables win over the file, valid values are still applied
+	assert.Equal(t, "../from-env", cfg.Paths.TerragruntDir)
+	assert.Equal(t, "file_vars.hcl", cfg.VarsFile)
+	assert.True(t, cfg.IsPluginCache)
+	assert.False(t, cfg.IsDebug)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockLoadConfig_Pause(t *testing.T) {
+	cases := map[string]time.Duration{
+		"2":     2 * time.Minute,
+		"45s":   45 * time.Second,
+		"1m30s": 90 * time.Second,
+		" 0 ":   0,
+	}
+
+	for value, expected := range cases {
+		t.Setenv("TT_PAUSE", value)
+
+		cfg, err := core.LoadConfig("")
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, cfg.Pause, value)
+	}
+
+	for _, value := range []string{"90x", "-1", "-5s", "soon"} {
+		t.Setenv("TT_PAUSE", value)
+
+		cfg, err := core.LoadConfig("")
+		require.ErrorIs(t, err, core.ErrInvalidDuration, value)
+		assert.Zero(t, cfg.Pause, value)
+	}
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockLoadConfig_MaxPause(t *testing.T) {
+	t.Setenv("TT_PAUSE", "1h")
+	t.Setenv("TT_PAUSE_MAX", "10m")
+
+	cfg, err := core.LoadConfig("")
+	require.ErrorIs(t, err, core.ErrPauseTooLong)
+
+	// The pause is capped even if the error is ignored
+	assert.Equal(t, 10*time.Minute, cfg.Pause)
+
+	cfg, err = core.LoadConfig("", core.WithPause(5*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.Pause)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockLoadConfig_DestroyMargin(t *testing.T) {
+	cfg, err := core.LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.DestroyMargin)
+
+	t.Setenv("TT_DESTROY_MARGIN", "90s")
+	cfg, err = core.LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, cfg.DestroyMargin)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockLoadConfig_InitRetries(t *testing.T) {
+	cfg, err := core.LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, 1, cfg.InitRetries)
+	assert.Equal(t, 10*time.Second, cfg.InitBackoff)
+
+	t.Setenv("TT_INIT_RETRIES", "3")
+	t.Setenv("TT_CLEAR_PLUGIN_DIR", "true")
+	cfg, err = core.LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, 3, cfg.InitRetries)
+	assert.True(t, cfg.ClearPluginDir)
+
+	t.Setenv("TT_INIT_RETRIES", "-1")
+	_, err = core.LoadConfig("")
+	require.ErrorIs(t, err, core.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), `TT_INIT_RETRIES: invalid count "-1"`)
+}
+
+func TestMockLoadConfig_Retry(t *testing.T) {
+	t.Parallel()
+
+	// Terratest already retries, ours are off unless configured.
+	cfg, err := core.LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, 1, cfg.Retry.MaxAttempts)
+
+	path := writeConfigFile(t, "config.yaml", `
+retry_max_attempts: 5
+retry_backoff: 1m
+retryable_errors:
+  "(?s).*Throttling.*": API throttled
+`)
+
+	cfg, err = core.LoadConfig(path, core.WithRetryableError("(?s).*RequestLimitExceeded.*", "request limit"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, cfg.Retry.MaxAttempts)
+	assert.Equal(t, time.Minute, cfg.Retry.Backoff)
+	assert.Equal(t, map[string]string{
+		"(?s).*Throttling.*":           "API throttled",
+		"(?s).*RequestLimitExceeded.*": "request limit",
+	}, cfg.Retry.RetryableErrors)
+
+	_, err = core.LoadConfig("", core.WithRetryableError("(unclosed", "broken"))
+	require.ErrorIs(t, err, core.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), `retryable error "(unclosed"`)
+
+	for _, attempts := range []string{"0", "-2"} {
+		path = writeConfigFile(t, "config.yaml", "retry_max_attempts: "+attempts)
+		cfg, err = core.LoadConfig(path)
+		require.ErrorIs(t, err, core.ErrInvalidConfig)
+		assert.Contains(t, err.Error(), "retry_max_attempts: must be at least 1, got "+attempts)
+		assert.Equal(t, 1, cfg.Retry.MaxAttempts)
+	}
+}
+
+func TestMockLoadConfig_InvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	// Options are validated after they are applied, not only the file and the environment
+	cfg, err := core.LoadConfig("", core.WithRetryMaxAttempts(0), core.WithInitRetries(-1))
+	require.ErrorIs(t, err, core.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), "retry max attempts: must be at least 1, got 0")
+	assert.Contains(t, err.Error(), "init retries: must not be negative, got -1")
+	assert.Equal(t, 1, cfg.Retry.MaxAttempts)
+	assert.Equal(t, 1, cfg.InitRetries)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockLoadConfig_RetryEnv(t *testing.T) {
+	t.Setenv("TT_RETRY_MAX_ATTEMPTS", "4")
+	cfg, err := core.LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, 4, cfg.Retry.MaxAttempts)
+
+	t.Setenv("TT_RETRY_MAX_ATTEMPTS", "0")
+	_, err = core.LoadConfig("")
+	require.ErrorIs(t, err, core.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), `TT_RETRY_MAX_ATTEMPTS: invalid attempts "0"`)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockLoadConfig_AWS(t *testing.T) {
+	path := writeConfigFile(t, "config.hcl", `
+aws_region  = "eu-west-1"
+assume_role = "terratest"
+vpc_id      = "vpc-0123456789abcdef0"
+`)
+
+	t.Setenv("TT_AWS_ACCOUNT_ID", "222222222222")
+	cfg, err := core.LoadConfig(path, core.WithAWSProfile("ci"))
+	require.NoError(t, err)
+
+	assert.Equal(t, core.AWSSettings{
+		Region:     "eu-west-1",
+		AccountID:  "222222222222",
+		Profile:    "ci",
+		AssumeRole: "terratest",
+		VPCID:      "vpc-0123456789abcdef0",
+	}, cfg.AWS)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockLoadConfig_AllowedAccountIDs(t *testing.T) {
+	cfg, err := core.LoadConfig("")
+	require.NoError(t, err)
+	assert.Empty(t, cfg.AllowedAccountIDs)
+
+	t.Setenv("TT_ALLOWED_ACCOUNT_IDS", "111111111111, 222222222222")
+	cfg, err = core.LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"111111111111", "222222222222"}, cfg.AllowedAccountIDs)
+
+	_, err = core.LoadConfig("", core.WithAllowedAccountIDs("1111"))
+	require.ErrorIs(t, err, core.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), `allowed account ID "1111"`)
+}
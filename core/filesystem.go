@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -16,6 +17,8 @@ type FileSystem interface {
 	RemoveAll(path string) error
 	ReadFile(path string) ([]byte, error)
 	WriteFile(filename string, data []byte, perm fs.FileMode) error
+	Stat(name string) (fs.FileInfo, error)
+	Remove(name string) error
 }
 
 // OsFileSystem is a real implementation of FileSystem.
@@ -40,6 +43,16 @@ func (OsFileSystem) WriteFile(filename string, data []byte, perm fs.FileMode) er
 	return os.WriteFile(filename, data, perm)
 }
 
+func (OsFileSystem) Stat(name string) (fs.FileInfo, error) {
+
+	return os.Stat(name)
+}
+
+func (OsFileSystem) Remove(name string) error {
+
+	return os.Remove(name)
+}
+
 // clearFolder removes all subfolders within the specified directory, excluding a specific subfolder.
 // It takes a FolderConfig struct as input containing the paths to relevant directories.
 func ClearFolder(t *testing.T, cfg RunTime, fs FileSystem) error {
@@ -72,35 +85,79 @@ func ClearFolder(t *testing.T, cfg RunTime, fs FileSystem) error {
 	return nil
 }
 
+// ClearPluginFolder removes every provider from the plugin cache directory, the directory itself is kept.
+func ClearPluginFolder(t *testing.T, cfg RunTime, fs FileSystem) error {
+	logger.Log(t, "Plugin cache folder clearing in progress")
+
+	entries, err := fs.ReadDir(cfg.Paths.TfPluginDir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(cfg.Paths.TfPluginDir, entry.Name())
+		if err := fs.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	logger.Log(t, "Plugin cache folder cleared")
+
+	return nil
+}
+
 func UpdateVarsFile(t *testing.T, cfg RunTime, fs FileSystem) ([]byte, error) {
 	logger.Log(t, "Update "+cfg.VarsFile)
 	rootVarsPath := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
 
-	// Read the current content.
-	currentContent, err := fs.ReadFile(rootVarsPath)
-	if err != nil {
-		return nil, fmt.Errorf("readFile func failed to read %s: %w", cfg.VarsFile, err)
+	// Serialize with other tests and processes sharing the vars file.
+	if cfg.IsLocking {
+		if err := lockVarsFile(t, cfg); err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", cfg.VarsFile, err)
+		}
 	}
 
-	// Store the original content.
-	originalContent := make([]byte, len(currentContent))
-	copy(originalContent, currentContent)
+	// Capture the original content so RestoreVarsFile can put it back.
+	_, original, err := snapshots.capture(t, fs, rootVarsPath)
+	if err != nil {
+		return nil, releaseOnError(t, cfg, fmt.Errorf("failed to capture %s: %w", cfg.VarsFile, err))
+	}
 
 	// Append or overwrite the content.
 	err = fs.WriteFile(rootVarsPath, []byte(cfg.Content), 0644)
 	if err != nil {
-		return nil, fmt.Errorf("writeFile func failed to write %s: %w", cfg.VarsFile, err)
+		return nil, releaseOnError(t, cfg, fmt.Errorf("writeFile func failed to write %s: %w", cfg.VarsFile, err))
 	}
 
 	logger.Log(t, "Updated "+cfg.VarsFile)
 
-	return originalContent, nil
+	return original.content, nil
 }
 
-// RestoreVarsFile restores the original content.
+// RestoreVarsFile restores the content captured by UpdateVarsFile or UpdateVarsLocals.
+// The file is removed if it did not exist before it was updated, a file that was never updated is left alone.
+// With locking enabled the vars file lock held by the test is released.
 func RestoreVarsFile(t *testing.T, cfg RunTime, fs FileSystem) error {
 	rootVarsPath := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
 	logger.Log(t, "Restore "+cfg.VarsFile)
 
-	return fs.WriteFile(rootVarsPath, []byte(cfg.Content), 0644)
+	err := snapshots.restore(fs, rootVarsPath)
+	if cfg.IsLocking {
+		if unlockErr := unlockVarsFile(t, cfg); unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to unlock %s: %w", cfg.VarsFile, unlockErr))
+		}
+	}
+
+	return err
+}
+
+// releaseOnError drops the vars file lock when an update fails half way.
+func releaseOnError(t *testing.T, cfg RunTime, err error) error {
+	if !cfg.IsLocking {
+		return err
+	}
+	if unlockErr := unlockVarsFile(t, cfg); unlockErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to unlock %s: %w", cfg.VarsFile, unlockErr))
+	}
+
+	return err
 }
@@ -6,9 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockFileSystem struct {
@@ -43,6 +46,55 @@ func (m *MockFileSystem) WriteFile(filename string, data []byte, perm fs.FileMod
 	return args.Error(0)
 }
 
+// Mock Stat method.
+func (m *MockFileSystem) Stat(name string) (fs.FileInfo, error) {
+	args := m.Called(name)
+
+	return args.Get(0).(fs.FileInfo), args.Error(1)
+}
+
+// Mock Remove method.
+func (m *MockFileSystem) Remove(name string) error {
+	args := m.Called(name)
+
+	return args.Error(0)
+}
+
+type MockFileInfo struct {
+	name string
+	mode fs.FileMode
+}
+
+func (m MockFileInfo) Name() string {
+
+	return m.name
+}
+
+func (m MockFileInfo) Size() int64 {
+
+	return 0
+}
+
+func (m MockFileInfo) Mode() fs.FileMode {
+
+	return m.mode
+}
+
+func (m MockFileInfo) ModTime() time.Time {
+
+	return time.Time{}
+}
+
+func (m MockFileInfo) IsDir() bool {
+
+	return m.mode.IsDir()
+}
+
+func (m MockFileInfo) Sys() any {
+
+	return nil
+}
+
 type MockDirEntry struct {
 	name  string
 	isDir bool
@@ -106,6 +158,31 @@ func TestMockClearFolder(t *testing.T) {
 	mockFS.AssertExpectations(t)
 }
 
+func TestMockClearPluginFolder(t *testing.T) {
+	t.Parallel()
+	mockFS := new(MockFileSystem)
+
+	cfg := core.RunTime{
+		Paths: core.FolderPaths{
+			TfPluginDir: "test/download-dir/.plugins",
+		},
+	}
+
+	mockEntries := []os.DirEntry{
+		MockDirEntry{name: "registry.terraform.io", isDir: true},
+		MockDirEntry{name: "lock", isDir: false},
+	}
+
+	mockFS.On("ReadDir", cfg.Paths.TfPluginDir).Return(mockEntries, nil)
+	mockFS.On("RemoveAll", filepath.Join(cfg.Paths.TfPluginDir, "registry.terraform.io")).Return(nil)
+	mockFS.On("RemoveAll", filepath.Join(cfg.Paths.TfPluginDir, "lock")).Return(nil)
+
+	err := core.ClearPluginFolder(t, cfg, mockFS)
+
+	require.NoError(t, err)
+	mockFS.AssertExpectations(t)
+}
+
 func TestMockUpdateVarsFile(t *testing.T) {
 	t.Parallel()
 	// Create a mock file system
@@ -115,16 +192,17 @@ func TestMockUpdateVarsFile(t *testing.T) {
 	cfg := core.RunTime{
 		VarsFile: "root_vars.hcl",
 		Paths: core.FolderPaths{
-			TerragruntDir: "test/terragrunt",
+			TerragruntDir: "test/update-vars",
 		},
 		Content: "new content",
 	}
 
 	rootVarsPath := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
 
-	// Set up the expected behavior for ReadFile
+	// Set up the expected behavior for ReadFile and Stat
 	originalContent := []byte("original content")
 	mockFS.On("ReadFile", rootVarsPath).Return(originalContent, nil)
+	mockFS.On("Stat", rootVarsPath).Return(MockFileInfo{name: cfg.VarsFile, mode: 0644}, nil)
 
 	// Set up the expected behavior for WriteFile
 	mockFS.On("WriteFile", rootVarsPath, []byte(cfg.Content), fs.FileMode(0644)).Return(nil)
@@ -153,20 +231,116 @@ func TestMockRestoreVarsFile(t *testing.T) {
 	cfg := core.RunTime{
 		VarsFile: "root_vars.hcl",
 		Paths: core.FolderPaths{
-			TerragruntDir: "test/terragrunt",
+			TerragruntDir: "test/restore-vars",
 		},
 		Content: "changed content",
 	}
 
 	rootVarsPath := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
+	originalContent := []byte("original content")
 
-	// Set up the expected behavior for WriteFile
-	mockFS.On("WriteFile", rootVarsPath, []byte(cfg.Content), fs.FileMode(0644)).Return(nil)
+	// Set up the expected behavior for the update
+	mockFS.On("ReadFile", rootVarsPath).Return(originalContent, nil)
+	mockFS.On("Stat", rootVarsPath).Return(MockFileInfo{name: cfg.VarsFile, mode: 0600}, nil)
+	mockFS.On("WriteFile", rootVarsPath, []byte(cfg.Content), fs.FileMode(0644)).Return(nil).Once()
+
+	// The restore must write the captured content with the captured mode
+	mockFS.On("WriteFile", rootVarsPath, originalContent, fs.FileMode(0600)).Return(nil).Once()
+
+	_, err := core.UpdateVarsFile(t, cfg, mockFS)
+	require.NoError(t, err)
 
 	if err := core.RestoreVarsFile(t, cfg, mockFS); err != nil {
 		t.Errorf("RestoreVarsFile returned error: %v", err)
 	}
 
+	// A second restore is a no-op
+	require.NoError(t, core.RestoreVarsFile(t, cfg, mockFS))
+
 	// Assert that all expectations were met
 	mockFS.AssertExpectations(t)
 }
+
+func TestMockRestoreVarsFile_NotExisting(t *testing.T) {
+	t.Parallel()
+	// Create a mock file system
+	mockFS := new(MockFileSystem)
+
+	// Define the configuration
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: "test/restore-missing-vars",
+		},
+		Content: "changed content",
+	}
+
+	rootVarsPath := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
+
+	// The file does not exist before the update, so the restore removes it
+	mockFS.On("ReadFile", rootVarsPath).Return([]byte(nil), fs.ErrNotExist)
+	mockFS.On("WriteFile", rootVarsPath, []byte(cfg.Content), fs.FileMode(0644)).Return(nil)
+	mockFS.On("Remove", rootVarsPath).Return(nil)
+
+	original, err := core.UpdateVarsFile(t, cfg, mockFS)
+	require.NoError(t, err)
+	assert.Empty(t, original)
+
+	require.NoError(t, core.RestoreVarsFile(t, cfg, mockFS))
+
+	// Assert that all expectations were met
+	mockFS.AssertExpectations(t)
+}
+
+func TestMockRestoreVarsFile_NoSnapshot(t *testing.T) {
+	t.Parallel()
+	// Create a mock file system
+	mockFS := new(MockFileSystem)
+
+	// Define the configuration
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: "test/never-updated",
+		},
+	}
+
+	// Without an update there is nothing to restore
+	require.NoError(t, core.RestoreVarsFile(t, cfg, mockFS))
+
+	// Nothing must be written
+	mockFS.AssertExpectations(t)
+}
+
+func TestMockUpdateVarsFile_SnapshotPerTest(t *testing.T) {
+	t.Parallel()
+
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: t.TempDir(),
+		},
+	}
+	rootVarsPath := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
+	require.NoError(t, os.WriteFile(rootVarsPath, []byte("original content"), 0644))
+
+	// The first test never restores, its snapshot ends with it
+	t.Run("first", func(t *testing.T) {
+		cfg.Content = "first content"
+		_, err := core.UpdateVarsFile(t, cfg, core.OsFileSystem{})
+		require.NoError(t, err)
+	})
+
+	t.Run("second", func(t *testing.T) {
+		cfg.Content = "second content"
+		original, err := core.UpdateVarsFile(t, cfg, core.OsFileSystem{})
+		require.NoError(t, err)
+		assert.Equal(t, "first content", string(original))
+
+		require.NoError(t, core.RestoreVarsFile(t, cfg, core.OsFileSystem{}))
+	})
+
+	content, err := os.ReadFile(rootVarsPath)
+	require.NoError(t, err)
+	assert.Equal(t, "first content", string(content))
+}
@@ -0,0 +1,193 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+const (
+	defaultLockRetryInterval = 2 * time.Second
+	cacheLockFile            = ".terratest-helpers.lock"
+)
+
+// FileLock is an exclusive advisory lock held on a lock file.
+type FileLock struct {
+	path string
+	file *os.File
+}
+
+// AcquireLock takes an exclusive advisory lock on path. It retries every cfg.LockRetryInterval
+// and gives up after cfg.LockTimeout, a zero timeout waits without limit.
+func AcquireLock(t *testing.T, cfg RunTime, path string) (*FileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory for %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+
+	interval := cfg.LockRetryInterval
+	if interval <= 0 {
+		interval = defaultLockRetryInterval
+	}
+	start := time.Now()
+
+	for {
+		locked, err := tryLock(file)
+		if err != nil {
+			file.Close()
+
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			logger.Log(t, "Acquired lock", path)
+
+			return &FileLock{path: path, file: file}, nil
+		}
+
+		if cfg.LockTimeout > 0 && time.Since(start)+interval > cfg.LockTimeout {
+			file.Close()
+
+			return nil, fmt.Errorf("%w after %s: %s", ErrLockTimeout, cfg.LockTimeout, path)
+		}
+
+		logger.Log(t, "Waiting for lock", path)
+		time.Sleep(interval)
+	}
+}
+
+// Release unlocks and closes the lock file.
+func (l *FileLock) Release() error {
+	if err := unlock(l.file); err != nil {
+		l.file.Close()
+
+		return fmt.Errorf("failed to unlock %s: %w", l.path, err)
+	}
+
+	return l.file.Close()
+}
+
+// CacheLockPath returns the lock file guarding the terragrunt download and plugin cache.
+func CacheLockPath(cfg RunTime) string {
+	return filepath.Join(cfg.Paths.TgDownloadDir, cacheLockFile)
+}
+
+// VarsLockPath returns the lock file guarding the vars file. It lives in the temp directory
+// so the Terragrunt tree is not polluted.
+func VarsLockPath(cfg RunTime) (string, error) {
+	abs, err := filepath.Abs(filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", cfg.VarsFile, err)
+	}
+	sum := sha256.Sum256([]byte(abs))
+
+	return filepath.Join(os.TempDir(), "terratest-helpers-"+hex.EncodeToString(sum[:8])+".lock"), nil
+}
+
+// heldLock tracks the vars file lock owned by a test, only that test releases it.
+type heldLock struct {
+	owner *testing.T
+	lock  *FileLock
+}
+
+var (
+	varsLocksMu sync.Mutex
+	varsLocks   = map[string]heldLock{}
+)
+
+// isSubtest reports whether t is a subtest of parent, subtests are named parent/child.
+func isSubtest(parent, t *testing.T) bool {
+	return strings.HasPrefix(t.Name(), parent.Name()+"/")
+}
+
+// lockVarsFile locks the vars file until unlockVarsFile is called by the same test, it is reentrant for that
+// test only. Other tests wait for the lock, subtests of the owner are refused with ErrVarsLockHeldByParent
+// as the owner cannot release it before they end. The lock is released when the owner ends at the latest.
+func lockVarsFile(t *testing.T, cfg RunTime) error {
+	path, err := VarsLockPath(cfg)
+	if err != nil {
+		return err
+	}
+
+	varsLocksMu.Lock()
+	held, ok := varsLocks[path]
+	varsLocksMu.Unlock()
+
+	if ok && held.owner == t {
+		return nil
+	}
+	if ok && isSubtest(held.owner, t) {
+		return fmt.Errorf("%w: %s holds %s", ErrVarsLockHeldByParent, held.owner.Name(), path)
+	}
+
+	// Every flock takes its own file description, tests of the same process wait for each other as well.
+	lock, err := AcquireLock(t, cfg, path)
+	if err != nil {
+		return err
+	}
+
+	varsLocksMu.Lock()
+	varsLocks[path] = heldLock{owner: t, lock: lock}
+	varsLocksMu.Unlock()
+
+	t.Cleanup(func() {
+		if release(path, lock) {
+			logger.Log(t, "Release vars file lock", path, "at the end of the test")
+			if err := lock.Release(); err != nil {
+				t.Errorf("failed to release %s: %v", path, err)
+			}
+		}
+	})
+
+	return nil
+}
+
+// release forgets lock if it is still the one held on path, it reports whether the caller has to release it.
+func release(path string, lock *FileLock) bool {
+	varsLocksMu.Lock()
+	defer varsLocksMu.Unlock()
+
+	held, ok := varsLocks[path]
+	if !ok || held.lock != lock {
+		return false
+	}
+	delete(varsLocks, path)
+
+	return true
+}
+
+// unlockVarsFile releases the vars file lock if t owns it, a lock of another test is left alone.
+func unlockVarsFile(t *testing.T, cfg RunTime) error {
+	path, err := VarsLockPath(cfg)
+	if err != nil {
+		return err
+	}
+
+	varsLocksMu.Lock()
+	held, ok := varsLocks[path]
+	varsLocksMu.Unlock()
+
+	if !ok || held.owner != t || !release(path, held.lock) {
+		return nil
+	}
+
+	return held.lock.Release()
+}
+
+var (
+	ErrLockTimeout          = errors.New("timed out waiting for lock")
+	ErrLockUnsupported      = errors.New("file locking is not supported on this platform")
+	ErrVarsLockHeldByParent = errors.New("the vars file lock is held by a parent test")
+)
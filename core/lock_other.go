@@ -0,0 +1,13 @@
+//go:build !unix
+
+package core
+
+import "os"
+
+func tryLock(_ *os.File) (bool, error) {
+	return false, ErrLockUnsupported
+}
+
+func unlock(_ *os.File) error {
+	return ErrLockUnsupported
+}
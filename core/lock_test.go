@@ -0,0 +1,171 @@
+package core_test
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockAcquireLock(t *testing.T) {
+	t.Parallel()
+
+	cfg := core.RunTime{
+		LockTimeout:       200 * time.Millisecond,
+		LockRetryInterval: 50 * time.Millisecond,
+	}
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	lock, err := core.AcquireLock(t, cfg, path)
+	require.NoError(t, err)
+
+	// A second lock on the same file times out while the first one is held
+	_, err = core.AcquireLock(t, cfg, path)
+	require.ErrorIs(t, err, core.ErrLockTimeout)
+
+	require.NoError(t, lock.Release())
+
+	// The lock is free again after release
+	lock, err = core.AcquireLock(t, cfg, path)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
+
+func TestMockUpdateVarsFile_Locking(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: dir,
+		},
+		Content:           "new content",
+		IsLocking:         true,
+		LockTimeout:       200 * time.Millisecond,
+		LockRetryInterval: 50 * time.Millisecond,
+	}
+
+	_, err := core.UpdateVarsFile(t, cfg, core.OsFileSystem{})
+	require.NoError(t, err)
+
+	// Another holder of the vars lock has to wait until the file is restored
+	lockPath, err := core.VarsLockPath(cfg)
+	require.NoError(t, err)
+	_, err = core.AcquireLock(t, cfg, lockPath)
+	require.ErrorIs(t, err, core.ErrLockTimeout)
+
+	require.NoError(t, core.RestoreVarsFile(t, cfg, core.OsFileSystem{}))
+
+	lock, err := core.AcquireLock(t, cfg, lockPath)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
+
+func TestMockUpdateVarsFile_SubtestOfOwner(t *testing.T) {
+	t.Parallel()
+
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: t.TempDir(),
+		},
+		Content:           "new content",
+		IsLocking:         true,
+		LockTimeout:       200 * time.Millisecond,
+		LockRetryInterval: 50 * time.Millisecond,
+	}
+	lockPath, err := core.VarsLockPath(cfg)
+	require.NoError(t, err)
+
+	_, err = core.UpdateVarsFile(t, cfg, core.OsFileSystem{})
+	require.NoError(t, err)
+
+	// A subtest neither shares nor releases the lock of its parent
+	t.Run("subtest", func(t *testing.T) {
+		_, err := core.UpdateVarsFile(t, cfg, core.OsFileSystem{})
+		require.ErrorIs(t, err, core.ErrVarsLockHeldByParent)
+
+		require.NoError(t, core.RestoreVarsFile(t, cfg, core.OsFileSystem{}))
+		_, err = core.AcquireLock(t, cfg, lockPath)
+		require.ErrorIs(t, err, core.ErrLockTimeout)
+	})
+
+	require.NoError(t, core.RestoreVarsFile(t, cfg, core.OsFileSystem{}))
+
+	lock, err := core.AcquireLock(t, cfg, lockPath)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
+
+func TestMockUpdateVarsFile_ParallelSiblings(t *testing.T) {
+	t.Parallel()
+
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: t.TempDir(),
+		},
+		Content:           "new content",
+		IsLocking:         true,
+		LockTimeout:       5 * time.Second,
+		LockRetryInterval: 10 * time.Millisecond,
+	}
+
+	var holders, maxHolders atomic.Int32
+	t.Run("group", func(t *testing.T) {
+		for _, name := range []string{"first", "second"} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := core.UpdateVarsFile(t, cfg, core.OsFileSystem{})
+				require.NoError(t, err)
+				current := holders.Add(1)
+				for {
+					seen := maxHolders.Load()
+					if current <= seen || maxHolders.CompareAndSwap(seen, current) {
+						break
+					}
+				}
+				time.Sleep(50 * time.Millisecond)
+				holders.Add(-1)
+
+				require.NoError(t, core.RestoreVarsFile(t, cfg, core.OsFileSystem{}))
+			})
+		}
+	})
+
+	// Siblings wait for each other instead of sharing the lock
+	assert.Equal(t, int32(1), maxHolders.Load())
+}
+
+func TestMockUpdateVarsFile_LockReleasedWithTest(t *testing.T) {
+	t.Parallel()
+
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: t.TempDir(),
+		},
+		Content:           "new content",
+		IsLocking:         true,
+		LockTimeout:       200 * time.Millisecond,
+		LockRetryInterval: 50 * time.Millisecond,
+	}
+	lockPath, err := core.VarsLockPath(cfg)
+	require.NoError(t, err)
+
+	// The subtest never restores, its lock ends with it
+	t.Run("update", func(t *testing.T) {
+		_, err := core.UpdateVarsFile(t, cfg, core.OsFileSystem{})
+		require.NoError(t, err)
+	})
+
+	lock, err := core.AcquireLock(t, cfg, lockPath)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
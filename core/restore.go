@@ -0,0 +1,123 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+// snapshot holds the state of a file before the first modification.
+type snapshot struct {
+	content  []byte
+	mode     fs.FileMode
+	existed  bool
+	restored bool
+}
+
+// snapshotStore keeps the captured snapshots keyed by absolute file path. A snapshot is dropped by the
+// cleanup of the test that captured it, so tests reusing a path do not see each other's snapshots.
+type snapshotStore struct {
+	mu    sync.Mutex
+	files map[string]*snapshot
+}
+
+var snapshots = &snapshotStore{files: map[string]*snapshot{}}
+
+// capture reads path and records its original state unless a snapshot is already pending.
+// It returns the current content together with the original snapshot.
+func (s *snapshotStore) capture(t *testing.T, fsys FileSystem, path string) ([]byte, snapshot, error) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return nil, snapshot{}, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, existed, mode, err := readWithMode(fsys, path)
+	if err != nil {
+		return nil, snapshot{}, err
+	}
+
+	// Keep the first capture until it has been restored, later updates must not overwrite the original.
+	if snap, ok := s.files[key]; ok && !snap.restored {
+		return current, *snap, nil
+	}
+
+	original := make([]byte, len(current))
+	copy(original, current)
+
+	snap := &snapshot{content: original, mode: mode, existed: existed}
+	s.files[key] = snap
+	t.Cleanup(func() { s.drop(t, key, snap) })
+
+	return current, *snap, nil
+}
+
+// drop forgets snap when the test that captured it ends, unless a newer snapshot replaced it.
+func (s *snapshotStore) drop(t *testing.T, key string, snap *snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.files[key] != snap {
+		return
+	}
+	if !snap.restored {
+		logger.Log(t, "Original content of", key, "was never restored")
+	}
+	delete(s.files, key)
+}
+
+// restore writes the captured content back to path, or removes path if it did not exist.
+// Without a snapshot, for example in a sandbox copy, there is nothing to restore.
+func (s *snapshotStore) restore(fsys FileSystem, path string) error {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Nothing changed since the last restore.
+	snap, ok := s.files[key]
+	if !ok || snap.restored {
+		return nil
+	}
+
+	if snap.existed {
+		if err := fsys.WriteFile(path, snap.content, snap.mode); err != nil {
+			return fmt.Errorf("writeFile func failed to write %s: %w", path, err)
+		}
+	} else {
+		if err := fsys.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	snap.restored = true
+
+	return nil
+}
+
+// readWithMode reads path and its permissions, a missing file is not an error.
+func readWithMode(fsys FileSystem, path string) ([]byte, bool, fs.FileMode, error) {
+	content, err := fsys.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, 0644, nil
+	}
+	if err != nil {
+		return nil, false, 0, fmt.Errorf("readFile func failed to read %s: %w", path, err)
+	}
+
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return nil, false, 0, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	return content, true, info.Mode().Perm(), nil
+}
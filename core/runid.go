@@ -0,0 +1,103 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+// RunIDTag is the tag carrying the run ID, example/terragrunt.hcl merges it with the mandatory tags.
+const RunIDTag = "tt_run_id"
+
+// ciJobVars hold the job ID of the common CI systems, the first one set is used.
+var ciJobVars = []string{"GITHUB_RUN_ID", "CI_JOB_ID", "BUILD_NUMBER", "CIRCLE_BUILD_NUM"}
+
+var (
+	runIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+	invalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// processRunID is shared by every RunTime of the test binary, so all configs of one run agree.
+var processRunID = sync.OnceValue(NewRunID)
+
+// NewRunID returns a short random ID, followed by the CI job ID when one is set, such as 3f9a1c-8812345.
+func NewRunID() string {
+	buf := make([]byte, 3)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("failed to generate run ID: %v", err))
+	}
+	id := hex.EncodeToString(buf)
+
+	for _, key := range ciJobVars {
+		if job := invalidChars.ReplaceAllString(strings.ToLower(os.Getenv(key)), ""); job != "" {
+			id += "-" + job
+
+			break
+		}
+	}
+	if len(id) > 32 {
+		id = id[:32]
+	}
+
+	return id
+}
+
+// ForTest returns a copy of r whose run ID is scoped to t, the run ID followed by a hash of t.Name(),
+// such as 3f9a1c-8812345-5d41ab. Parallel tests sharing a config then get names and tags of their own,
+// and a rerun with the same TT_RUN_ID derives the same ID for the same test.
+func (r RunTime) ForTest(t *testing.T) RunTime {
+	t.Helper()
+
+	sum := sha256.Sum256([]byte(t.Name()))
+	suffix := "-" + hex.EncodeToString(sum[:3])
+	base := r.RunID
+	if base == "" {
+		base = processRunID()
+	}
+	if len(base)+len(suffix) > 32 {
+		base = strings.TrimRight(base[:32-len(suffix)], "-")
+	}
+
+	r.RunID = base + suffix
+	logger.Log(t, "Run ID of", t.Name(), "is", r.RunID)
+
+	return r
+}
+
+// UniqueName appends the run ID to base, such as TestDummy-us-east-1-3f9a1c-8812345.
+func (r RunTime) UniqueName(base string) string {
+	if r.RunID == "" {
+		return base
+	}
+
+	return base + "-" + r.RunID
+}
+
+// RunEnv returns the variables exposing the run ID to terragrunt, through get_env("TT_RUN_ID") in
+// the configuration and as the tt_run_id input of every module.
+func (r RunTime) RunEnv() map[string]string {
+	if r.RunID == "" {
+		return map[string]string{}
+	}
+
+	return map[string]string{
+		"TT_RUN_ID":          r.RunID,
+		"TF_VAR_" + RunIDTag: r.RunID,
+	}
+}
+
+func validateRunID(id string) error {
+	if !runIDPattern.MatchString(id) {
+		return fmt.Errorf("%w: %q, use up to 32 lowercase letters, digits and dashes", ErrInvalidRunID, id)
+	}
+
+	return nil
+}
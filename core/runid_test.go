@@ -0,0 +1,76 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockNewRunID(t *testing.T) {
+	t.Setenv("GITHUB_RUN_ID", "")
+	t.Setenv("CI_JOB_ID", "")
+	t.Setenv("BUILD_NUMBER", "")
+	t.Setenv("CIRCLE_BUILD_NUM", "")
+
+	id := core.NewRunID()
+	assert.Regexp(t, `^[0-9a-f]{6}$`, id)
+	assert.NotEqual(t, id, core.NewRunID())
+
+	t.Setenv("CI_JOB_ID", "8812345")
+	assert.Regexp(t, `^[0-9a-f]{6}-8812345$`, core.NewRunID())
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockLoadConfig_RunID(t *testing.T) {
+	cfg, err := core.LoadConfig("")
+	require.NoError(t, err)
+	require.NotEmpty(t, cfg.RunID)
+
+	other, err := core.LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, cfg.RunID, other.RunID, "configs of one test binary share the run ID")
+
+	t.Setenv("TT_RUN_ID", "rerun-42")
+	cfg, err = core.LoadConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "rerun-42", cfg.RunID)
+	assert.Equal(t, "TestDummy-us-east-1-rerun-42", cfg.UniqueName("TestDummy-us-east-1"))
+	assert.Equal(t, map[string]string{"TT_RUN_ID": "rerun-42", "TF_VAR_tt_run_id": "rerun-42"}, cfg.RunEnv())
+
+	t.Setenv("TT_RUN_ID", "Not Valid")
+	cfg, err = core.LoadConfig("")
+	require.ErrorIs(t, err, core.ErrInvalidRunID)
+	assert.Equal(t, other.RunID, cfg.RunID)
+}
+
+func TestMockUniqueName_NoRunID(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "TestDummy", core.RunTime{}.UniqueName("TestDummy"))
+	assert.Empty(t, core.RunTime{}.RunEnv())
+}
+
+func TestMockRunTime_ForTest(t *testing.T) {
+	t.Parallel()
+
+	cfg := core.RunTime{RunID: "3f9a1c-8812345"}
+	ids := map[string]string{}
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			scoped := cfg.ForTest(t)
+			assert.Regexp(t, `^3f9a1c-8812345-[0-9a-f]{6}$`, scoped.RunID)
+			assert.Equal(t, scoped.RunID, cfg.ForTest(t).RunID, "the ID of a test is stable")
+			assert.Equal(t, "TestDummy-"+scoped.RunID, scoped.UniqueName("TestDummy"))
+			ids[name] = scoped.RunID
+		})
+	}
+	assert.NotEqual(t, ids["first"], ids["second"], "parallel tests get IDs of their own")
+	assert.Equal(t, "3f9a1c-8812345", cfg.RunID, "the shared config is not changed")
+
+	long := core.RunTime{RunID: "3f9a1c-12345678901234567890123"}.ForTest(t)
+	assert.Regexp(t, `^[a-z0-9][a-z0-9-]{0,31}$`, long.RunID)
+	assert.NotEmpty(t, core.RunTime{}.ForTest(t).RunID)
+}
@@ -0,0 +1,265 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+// skippedDirs hold downloaded code, they are never copied into the sandbox or searched for modules.
+var skippedDirs = map[string]bool{
+	".git":              true,
+	".terraform":        true,
+	".terragrunt-cache": true,
+}
+
+// skippedFiles are never copied into the sandbox, state belongs to the original tree.
+var skippedFiles = map[string]bool{
+	"terraform.tfstate":        true,
+	"terraform.tfstate.backup": true,
+}
+
+var (
+	// findInParentRegex matches find_in_parent_folders() calls and captures the optional file name.
+	findInParentRegex = regexp.MustCompile(`find_in_parent_folders\(\s*(?:"([^"]*)")?`)
+	// parentDirRegex matches paths like ${dirname(find_in_parent_folders())}/_envcommon/... and captures the first directory.
+	parentDirRegex = regexp.MustCompile(`dirname\(find_in_parent_folders\(\s*(?:"([^"]*)")?[^)]*\)\)\}/([^/"$]+)`)
+)
+
+// TerragruntPath joins elem to the Terragrunt directory, e.g. cfg.TerragruntPath("app", "iam").
+func (r RunTime) TerragruntPath(elem ...string) string {
+	return filepath.Join(append([]string{r.Paths.TerragruntDir}, elem...)...)
+}
+
+// CopyTerragruntDirToTemp copies the Terragrunt tree into a temporary directory owned by the test,
+// writes cfg.Content to the copied vars file and returns a RunTime pointing at the copy.
+// Files referenced through find_in_parent_folders that live above the tree are copied alongside it.
+func CopyTerragruntDirToTemp(t *testing.T, cfg RunTime) (RunTime, error) {
+	sandbox, err := copyToSandbox(t, cfg)
+	if err != nil {
+		return RunTime{}, err
+	}
+
+	varsPath := filepath.Join(sandbox.Paths.TerragruntDir, sandbox.VarsFile)
+	if err := os.WriteFile(varsPath, []byte(cfg.Content), 0644); err != nil {
+		return RunTime{}, fmt.Errorf("writeFile func failed to write %s: %w", varsPath, err)
+	}
+
+	return sandbox, nil
+}
+
+// CopyTerragruntDirToTempWithLocals works like CopyTerragruntDirToTemp but patches the locals
+// of the copied vars file instead of overwriting it.
+func CopyTerragruntDirToTempWithLocals(t *testing.T, cfg RunTime, patch LocalsPatch) (RunTime, error) {
+	sandbox, err := copyToSandbox(t, cfg)
+	if err != nil {
+		return RunTime{}, err
+	}
+
+	varsPath := filepath.Join(sandbox.Paths.TerragruntDir, sandbox.VarsFile)
+	content, err := os.ReadFile(varsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return RunTime{}, fmt.Errorf("readFile func failed to read %s: %w", varsPath, err)
+	}
+
+	patched, err := PatchLocals(content, varsPath, patch)
+	if err != nil {
+		return RunTime{}, err
+	}
+
+	if err := os.WriteFile(varsPath, patched, 0644); err != nil {
+		return RunTime{}, fmt.Errorf("writeFile func failed to write %s: %w", varsPath, err)
+	}
+
+	return sandbox, nil
+}
+
+func copyToSandbox(t *testing.T, cfg RunTime) (RunTime, error) {
+	src, err := filepath.Abs(cfg.Paths.TerragruntDir)
+	if err != nil {
+		return RunTime{}, fmt.Errorf("failed to resolve %s: %w", cfg.Paths.TerragruntDir, err)
+	}
+
+	root := t.TempDir()
+	dst := filepath.Join(root, filepath.Base(src))
+	logger.Log(t, "Copy", src, "to", dst)
+
+	if err := copyTree(src, dst); err != nil {
+		return RunTime{}, fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+
+	if err := copyParentIncludes(src, root); err != nil {
+		return RunTime{}, err
+	}
+
+	sandbox := cfg
+	sandbox.Paths.TerragruntDir = dst
+
+	return sandbox, nil
+}
+
+// copyTree recursively copies src to dst, skipping caches and state files.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case entry.IsDir():
+			if SkippedDir(entry.Name()) {
+				return filepath.SkipDir
+			}
+
+			return os.MkdirAll(target, 0755)
+		case skippedFiles[entry.Name()]:
+			return nil
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target)
+		}
+	})
+}
+
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(dst, content, info.Mode().Perm())
+}
+
+// copyParentIncludes copies files that HCL files in src find in parent folders above src into root,
+// together with directories referenced relative to them such as _envcommon.
+func copyParentIncludes(src, root string) error {
+	names, dirs, err := parentReferences(src)
+	if err != nil {
+		return err
+	}
+
+	for name := range names {
+		// Found inside the tree, nothing to do.
+		if _, err := os.Stat(filepath.Join(src, name)); err == nil {
+			continue
+		}
+
+		found, ok := FindInParents(filepath.Dir(src), name)
+		if !ok {
+			continue
+		}
+
+		if err := copyFile(found, filepath.Join(root, name)); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", found, err)
+		}
+
+		for _, dir := range dirs[name] {
+			from := filepath.Join(filepath.Dir(found), dir)
+			if _, err := os.Stat(from); err != nil {
+				continue
+			}
+			if err := copyTree(from, filepath.Join(root, dir)); err != nil {
+				return fmt.Errorf("failed to copy %s: %w", from, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parentReferences collects the file names passed to find_in_parent_folders in src
+// and the directories referenced next to them.
+func parentReferences(src string) (map[string]bool, map[string][]string, error) {
+	names := map[string]bool{}
+	dirs := map[string][]string{}
+
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && SkippedDir(entry.Name()) {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".hcl") {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		for _, match := range findInParentRegex.FindAllStringSubmatch(string(content), -1) {
+			names[defaultParentName(match[1])] = true
+		}
+		for _, match := range parentDirRegex.FindAllStringSubmatch(string(content), -1) {
+			name := defaultParentName(match[1])
+			dirs[name] = append(dirs[name], match[2])
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to scan %s: %w", src, err)
+	}
+
+	return names, dirs, nil
+}
+
+// defaultParentName mirrors find_in_parent_folders, which looks for terragrunt.hcl without arguments.
+func defaultParentName(name string) string {
+	if name == "" {
+		return "terragrunt.hcl"
+	}
+
+	return name
+}
+
+// SkippedDir reports whether a directory named name holds downloaded code, like .terragrunt-cache,
+// and is left out when walking a Terragrunt tree.
+func SkippedDir(name string) bool {
+	return skippedDirs[name]
+}
+
+// FindInParents walks up from dir and returns the first path containing name.
+func FindInParents(dir, name string) (string, bool) {
+	for {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
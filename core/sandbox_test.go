@@ -0,0 +1,100 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestMockCopyTerragruntDirToTemp(t *testing.T) {
+	t.Parallel()
+
+	// parent/ holds files the tree finds in parent folders, parent/live is the Terragrunt directory
+	parent := t.TempDir()
+	writeTestFile(t, filepath.Join(parent, "terragrunt.hcl"), `remote_state {}`)
+	writeTestFile(t, filepath.Join(parent, "mandatory_tags.hcl"), `locals {}`)
+	writeTestFile(t, filepath.Join(parent, "_envcommon", "iam-policy.hcl"), `inputs = {}`)
+	writeTestFile(t, filepath.Join(parent, "live", "root_vars.hcl"), `locals { aws_region = "us-east-1" }`)
+	writeTestFile(t, filepath.Join(parent, "live", "app", "iam", "terragrunt.hcl"), `
+locals {
+  tags = read_terragrunt_config(find_in_parent_folders("mandatory_tags.hcl"))
+}
+
+include "_envcommon" {
+  path = "${dirname(find_in_parent_folders())}/_envcommon/iam-policy.hcl"
+}
+`)
+	writeTestFile(t, filepath.Join(parent, "live", "app", "iam", ".terragrunt-cache", "junk"), "junk")
+	writeTestFile(t, filepath.Join(parent, "live", "app", "iam", "terraform.tfstate"), "{}")
+
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: filepath.Join(parent, "live"),
+		},
+		Content: "locals {}",
+	}
+
+	sandbox, err := core.CopyTerragruntDirToTemp(t, cfg)
+	require.NoError(t, err)
+	assert.NotEqual(t, cfg.Paths.TerragruntDir, sandbox.Paths.TerragruntDir)
+
+	// The vars override only lands in the copy
+	content, err := os.ReadFile(sandbox.TerragruntPath("root_vars.hcl"))
+	require.NoError(t, err)
+	assert.Equal(t, "locals {}", string(content))
+
+	original, err := os.ReadFile(cfg.TerragruntPath("root_vars.hcl"))
+	require.NoError(t, err)
+	assert.Contains(t, string(original), "us-east-1")
+
+	// Modules are copied, caches and state are not
+	assert.FileExists(t, sandbox.TerragruntPath("app", "iam", "terragrunt.hcl"))
+	assert.NoDirExists(t, sandbox.TerragruntPath("app", "iam", ".terragrunt-cache"))
+	assert.NoFileExists(t, sandbox.TerragruntPath("app", "iam", "terraform.tfstate"))
+
+	// Parent folder includes are copied above the sandbox
+	sandboxParent := filepath.Dir(sandbox.Paths.TerragruntDir)
+	assert.FileExists(t, filepath.Join(sandboxParent, "terragrunt.hcl"))
+	assert.FileExists(t, filepath.Join(sandboxParent, "mandatory_tags.hcl"))
+	assert.FileExists(t, filepath.Join(sandboxParent, "_envcommon", "iam-policy.hcl"))
+}
+
+func TestMockCopyTerragruntDirToTempWithLocals(t *testing.T) {
+	t.Parallel()
+
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: "../example",
+		},
+	}
+
+	patch := core.LocalsPatch{
+		Set: map[string]cty.Value{"environment": cty.StringVal("sandbox")},
+	}
+
+	sandbox, err := core.CopyTerragruntDirToTempWithLocals(t, cfg, patch)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(sandbox.TerragruntPath("root_vars.hcl"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"sandbox"`)
+	assert.Contains(t, string(content), `aws_region  = "us-east-1"`)
+
+	// The tree includes the shared configuration used through find_in_parent_folders
+	assert.FileExists(t, sandbox.TerragruntPath("mandatory_tags.hcl"))
+	assert.FileExists(t, sandbox.TerragruntPath("_envcommon", "iam-policy.hcl"))
+	assert.FileExists(t, sandbox.TerragruntPath("app", "iam2", "terragrunt.hcl"))
+}
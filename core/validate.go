@@ -0,0 +1,119 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+var (
+	// MinTerragruntVersion is the oldest terragrunt release the helpers are tested with.
+	MinTerragruntVersion = "0.48.0"
+	// MinTerraformVersion matches required_version in the example versions.tf.
+	MinTerraformVersion = "1.4.1"
+)
+
+var versionRegex = regexp.MustCompile(`v?(\d+\.\d+\.\d+)`)
+
+// Validate checks the RunTime before any terragrunt command runs and returns every problem found.
+func (r RunTime) Validate() error {
+	var errs []error
+
+	errs = append(errs, r.validateTerragruntDir()...)
+	errs = appendErr(errs, validateWritableDir("download dir", r.Paths.TgDownloadDir))
+	errs = appendErr(errs, validateWritableDir("plugin dir", r.Paths.TfPluginDir))
+	errs = appendErr(errs, validateBinary("terragrunt", []string{"--version"}, MinTerragruntVersion))
+	errs = appendErr(errs, validateBinary("terraform", []string{"version"}, MinTerraformVersion))
+
+	if _, diags := hclsyntax.ParseConfig([]byte(r.Content), "content", hcl.InitialPos); diags.HasErrors() {
+		errs = append(errs, fmt.Errorf("content is not valid HCL: %w", diags))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w:\n%w", ErrInvalidRunTime, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func (r RunTime) validateTerragruntDir() []error {
+	dir := r.Paths.TerragruntDir
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return []error{fmt.Errorf("terragrunt dir %s: %w", dir, err)}
+	}
+	if !info.IsDir() {
+		return []error{fmt.Errorf("terragrunt dir %s is not a directory", dir)}
+	}
+
+	var errs []error
+	if _, err := os.Stat(filepath.Join(dir, "terragrunt.hcl")); err != nil {
+		errs = append(errs, fmt.Errorf("terragrunt dir %s has no terragrunt.hcl: %w", dir, err))
+	}
+	if _, err := os.Stat(filepath.Join(dir, r.VarsFile)); err != nil {
+		errs = append(errs, fmt.Errorf("vars file %s: %w", r.VarsFile, err))
+	}
+
+	return errs
+}
+
+// validateWritableDir creates dir if needed and checks a file can be written into it.
+func validateWritableDir(name, dir string) error {
+	if dir == "" {
+		return fmt.Errorf("%s is not set", name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("%s %s cannot be created: %w", name, dir, err)
+	}
+
+	probe, err := os.CreateTemp(dir, ".terratest-helpers-probe-*")
+	if err != nil {
+		return fmt.Errorf("%s %s is not writable: %w", name, dir, err)
+	}
+	probe.Close()
+
+	return os.Remove(probe.Name())
+}
+
+// validateBinary checks name is on PATH and reports at least minVersion.
+func validateBinary(name string, args []string, minVersion string) error {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("%s binary not found on PATH: %w", name, err)
+	}
+
+	output, err := exec.Command(path, args...).Output()
+	if err != nil {
+		return fmt.Errorf("failed to get %s version: %w", name, err)
+	}
+
+	match := versionRegex.FindStringSubmatch(string(output))
+	if match == nil {
+		return fmt.Errorf("failed to parse %s version from %q", name, output)
+	}
+
+	current, err := version.NewVersion(match[1])
+	if err != nil {
+		return fmt.Errorf("failed to parse %s version %s: %w", name, match[1], err)
+	}
+	minimum, err := version.NewVersion(minVersion)
+	if err != nil {
+		return fmt.Errorf("failed to parse minimum %s version %s: %w", name, minVersion, err)
+	}
+
+	if current.LessThan(minimum) {
+		return fmt.Errorf("%s version %s is older than the required %s", name, current, minimum)
+	}
+
+	return nil
+}
+
+var ErrInvalidRunTime = errors.New("invalid run time")
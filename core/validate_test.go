@@ -0,0 +1,72 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFakeBinary(t *testing.T, dir, name, output string) {
+	t.Helper()
+	script := "#!/bin/sh\necho '" + output + "'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(script), 0755))
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockValidate(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeBinary(t, binDir, "terragrunt", "terragrunt version v0.55.1")
+	writeFakeBinary(t, binDir, "terraform", "Terraform v1.5.7\non linux_amd64")
+	t.Setenv("PATH", binDir)
+
+	cacheDir := t.TempDir()
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: "../example",
+			TgDownloadDir: filepath.Join(cacheDir, "download"),
+			TfPluginDir:   filepath.Join(cacheDir, "download", ".plugins"),
+		},
+		Content: `locals { aws_region = "us-east-1" }`,
+	}
+
+	require.NoError(t, cfg.Validate())
+	assert.DirExists(t, cfg.Paths.TfPluginDir)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockValidate_Failure(t *testing.T) {
+	binDir := t.TempDir()
+	writeFakeBinary(t, binDir, "terragrunt", "terragrunt version v0.30.0")
+	t.Setenv("PATH", binDir)
+
+	// A regular file blocks the creation of the cache directories
+	blocker := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(blocker, nil, 0644))
+
+	cfg := core.RunTime{
+		VarsFile: "missing_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: "../example/app",
+			TgDownloadDir: filepath.Join(blocker, "download"),
+			TfPluginDir:   filepath.Join(blocker, "plugins"),
+		},
+		Content: `locals {`,
+	}
+
+	err := cfg.Validate()
+	require.ErrorIs(t, err, core.ErrInvalidRunTime)
+
+	// Every problem is reported at once
+	assert.Contains(t, err.Error(), "has no terragrunt.hcl")
+	assert.Contains(t, err.Error(), "vars file missing_vars.hcl")
+	assert.Contains(t, err.Error(), "download dir")
+	assert.Contains(t, err.Error(), "plugin dir")
+	assert.Contains(t, err.Error(), "terragrunt version 0.30.0 is older than the required 0.48.0")
+	assert.Contains(t, err.Error(), "terraform binary not found on PATH")
+	assert.Contains(t, err.Error(), "content is not valid HCL")
+}
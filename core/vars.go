@@ -0,0 +1,134 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// LocalsPatch describes attribute changes applied to the `locals` block of a vars file.
+type LocalsPatch struct {
+	// Set overrides existing attributes or adds missing ones.
+	Set map[string]cty.Value
+	// Delete removes attributes from the block.
+	Delete []string
+}
+
+// PatchLocals applies the patch to the first `locals` block in src.
+// Comments, ordering and formatting of untouched attributes are preserved.
+func PatchLocals(src []byte, filename string, patch LocalsPatch) ([]byte, error) {
+	file, diags := hclwrite.ParseConfig(src, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, diags)
+	}
+
+	locals := file.Body().FirstMatchingBlock("locals", nil)
+	if locals == nil {
+		locals = file.Body().AppendNewBlock("locals", nil)
+	}
+	body := locals.Body()
+
+	for _, name := range patch.Delete {
+		if body.RemoveAttribute(name) == nil {
+			return nil, fmt.Errorf("%w: %s in %s", ErrLocalNotFound, name, filename)
+		}
+	}
+
+	// Sort names so new attributes are appended in a stable order.
+	names := make([]string, 0, len(patch.Set))
+	for name := range patch.Set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		body.SetAttributeValue(name, patch.Set[name])
+	}
+
+	return file.Bytes(), nil
+}
+
+// UpdateVarsLocals edits individual locals of the vars file instead of replacing the whole file.
+// It returns the original content of the file.
+func UpdateVarsLocals(t *testing.T, cfg RunTime, fs FileSystem, patch LocalsPatch) ([]byte, error) {
+	logger.Log(t, "Patch locals in "+cfg.VarsFile)
+	rootVarsPath := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
+
+	// Serialize with other tests and processes sharing the vars file.
+	if cfg.IsLocking {
+		if err := lockVarsFile(t, cfg); err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", cfg.VarsFile, err)
+		}
+	}
+
+	// Capture the original content so RestoreVarsFile can put it back.
+	currentContent, original, err := snapshots.capture(t, fs, rootVarsPath)
+	if err != nil {
+		return nil, releaseOnError(t, cfg, fmt.Errorf("failed to capture %s: %w", cfg.VarsFile, err))
+	}
+
+	patched, err := PatchLocals(currentContent, rootVarsPath, patch)
+	if err != nil {
+		return nil, releaseOnError(t, cfg, err)
+	}
+
+	if err := fs.WriteFile(rootVarsPath, patched, 0644); err != nil {
+		return nil, releaseOnError(t, cfg, fmt.Errorf("writeFile func failed to write %s: %w", cfg.VarsFile, err))
+	}
+
+	logger.Log(t, "Patched locals in "+cfg.VarsFile)
+
+	return original.content, nil
+}
+
+// ReadVarsLocals returns the locals of the vars file that are plain strings, numbers or bools, as strings.
+// cfg.Content is read instead when the vars file does not exist.
+func ReadVarsLocals(cfg RunTime, fsys FileSystem) (map[string]string, error) {
+	rootVarsPath := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
+
+	content, err := fsys.ReadFile(rootVarsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		rootVarsPath, content, err = "content", []byte(cfg.Content), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("readFile func failed to read %s: %w", cfg.VarsFile, err)
+	}
+
+	file, diags := hclsyntax.ParseConfig(content, rootVarsPath, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %w", rootVarsPath, diags)
+	}
+
+	locals := map[string]string{}
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "locals" {
+			continue
+		}
+		for name, attr := range block.Body.Attributes {
+			// Locals using functions or other locals are skipped, as are lists and maps.
+			value, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || !value.Type().IsPrimitiveType() || value.IsNull() {
+				continue
+			}
+			str, err := convert.Convert(value, cty.String)
+			if err != nil {
+				continue
+			}
+			locals[name] = str.AsString()
+		}
+	}
+
+	return locals, nil
+}
+
+var ErrLocalNotFound = errors.New("local not found")
@@ -0,0 +1,150 @@
+package core_test
+
+import (
+	"bytes"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const testRootVars = `# Root level variables.
+locals {
+  account_id  = "111111111111"
+  aws_region  = "us-east-1" # region used by the provider
+  environment = "test"
+  tenant      = "tt"
+}
+`
+
+func TestMockPatchLocals(t *testing.T) {
+	t.Parallel()
+
+	patch := core.LocalsPatch{
+		Set: map[string]cty.Value{
+			"aws_region": cty.StringVal("eu-west-1"),
+			"namespace":  cty.StringVal("none"),
+		},
+		Delete: []string{"tenant"},
+	}
+
+	result, err := core.PatchLocals([]byte(testRootVars), "root_vars.hcl", patch)
+	require.NoError(t, err)
+
+	expected := `# Root level variables.
+locals {
+  account_id  = "111111111111"
+  aws_region  = "eu-west-1" # region used by the provider
+  environment = "test"
+  namespace   = "none"
+}
+`
+	assert.Equal(t, expected, string(result))
+}
+
+func TestMockPatchLocals_MissingLocal(t *testing.T) {
+	t.Parallel()
+
+	patch := core.LocalsPatch{
+		Delete: []string{"does_not_exist"},
+	}
+
+	_, err := core.PatchLocals([]byte(testRootVars), "root_vars.hcl", patch)
+	require.ErrorIs(t, err, core.ErrLocalNotFound)
+}
+
+func TestMockPatchLocals_InvalidHCL(t *testing.T) {
+	t.Parallel()
+
+	_, err := core.PatchLocals([]byte("locals {"), "root_vars.hcl", core.LocalsPatch{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse")
+}
+
+func TestMockUpdateVarsLocals(t *testing.T) {
+	t.Parallel()
+	// Create a mock file system
+	mockFS := new(MockFileSystem)
+
+	// Define the configuration
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths: core.FolderPaths{
+			TerragruntDir: "test/update-locals",
+		},
+	}
+
+	rootVarsPath := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
+	originalContent := []byte(testRootVars)
+
+	mockFS.On("ReadFile", rootVarsPath).Return(originalContent, nil)
+	mockFS.On("Stat", rootVarsPath).Return(MockFileInfo{name: cfg.VarsFile, mode: 0644}, nil)
+	mockFS.On("WriteFile", rootVarsPath, mock.MatchedBy(func(data []byte) bool {
+		return bytes.Contains(data, []byte(`environment = "prod"`)) && bytes.Contains(data, []byte("# region used by the provider"))
+	}), fs.FileMode(0644)).Return(nil)
+
+	patch := core.LocalsPatch{
+		Set: map[string]cty.Value{"environment": cty.StringVal("prod")},
+	}
+
+	result, err := core.UpdateVarsLocals(t, cfg, mockFS, patch)
+	require.NoError(t, err)
+
+	// Assert that the returned original content is correct
+	assert.Equal(t, originalContent, result)
+
+	mockFS.AssertExpectations(t)
+}
+
+func TestMockReadVarsLocals(t *testing.T) {
+	t.Parallel()
+	mockFS := new(MockFileSystem)
+
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths:    core.FolderPaths{TerragruntDir: "test/read-locals"},
+		Content:  "locals {\n  aws_region = \"eu-west-1\"\n}\n",
+	}
+	rootVarsPath := filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)
+	mockFS.On("ReadFile", rootVarsPath).Return([]byte(testRootVars+`locals {
+  stage       = "aws-account"
+  dept_code   = 000
+  label_order = ["environment", "tenant"]
+  name        = "${local.tenant}-test"
+}
+`), nil)
+
+	locals, err := core.ReadVarsLocals(cfg, mockFS)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"account_id":  "111111111111",
+		"aws_region":  "us-east-1",
+		"environment": "test",
+		"tenant":      "tt",
+		"stage":       "aws-account",
+		"dept_code":   "0",
+	}, locals)
+	mockFS.AssertExpectations(t)
+}
+
+func TestMockReadVarsLocals_Content(t *testing.T) {
+	t.Parallel()
+	mockFS := new(MockFileSystem)
+
+	cfg := core.RunTime{
+		VarsFile: "root_vars.hcl",
+		Paths:    core.FolderPaths{TerragruntDir: "test/read-content"},
+		Content:  "locals {\n  aws_region = \"eu-west-1\"\n}\n",
+	}
+	mockFS.On("ReadFile", filepath.Join(cfg.Paths.TerragruntDir, cfg.VarsFile)).Return([]byte(nil), fs.ErrNotExist)
+
+	locals, err := core.ReadVarsLocals(cfg, mockFS)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"aws_region": "eu-west-1"}, locals)
+}
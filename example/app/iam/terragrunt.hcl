@@ -19,7 +19,7 @@ include "root" {
 # ---------------------------------------------------------------------------------------------------------------------
 
 inputs = {
-  iam_policy_name = "DummyTest-${local.root_vars.locals.aws_region}"
+  iam_policy_name = "DummyTest-${local.root_vars.locals.aws_region}-${get_env("TT_RUN_ID", "local")}"
   iam_source_policy_documents = [
     jsonencode({
       Version = "2012-10-17"
@@ -9,7 +9,10 @@ locals {
   #extra_vars = read_terragrunt_config(find_in_parent_folders("app.hcl", "infra.hcl"), { locals = {} })
   mandatory_tags = read_terragrunt_config(find_in_parent_folders("mandatory_tags.hcl"), { locals = { mandatory_tags = {} } })
   child_tags     = read_terragrunt_config(find_in_parent_folders("child_tags.hcl"), { locals = { child_tags = {} } })
-  merged_tags    = merge(local.mandatory_tags.locals.mandatory_tags, local.child_tags.locals.child_tags)
+  # TT_RUN_ID is set by the terratest helpers, resources of each test run can be traced and swept by it.
+  run_id         = get_env("TT_RUN_ID", "local")
+  run_tags       = { tt_run_id = local.run_id }
+  merged_tags    = merge(local.mandatory_tags.locals.mandatory_tags, local.child_tags.locals.child_tags, local.run_tags)
   #populate tags map
   tags_map = { locals = { tags = local.merged_tags } }
 
@@ -75,7 +78,8 @@ remote_state {
 
 inputs = merge(
   local.root_vars.locals,
-  local.tags_map.locals
+  local.tags_map.locals,
+  { tt_run_id = local.run_id }
 )
 
 terraform {
@@ -3,96 +3,113 @@ package awsutils
 import (
 	"context"
 	"fmt"
+	"os"
 	"testing"
 
-	"github.com/GoGstickGo/terratest-helpers/pkg/parameters"
+	"github.com/GoGstickGo/terratest-helpers/core"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/workmail"
 	"github.com/gruntwork-io/terratest/modules/logger"
 )
 
 // AWS config.
-type DefaultAWSConfigLoader struct{}
-
-// LoadConfig loads the AWS configuration using the AWS SDK.
-func (d *DefaultAWSConfigLoader) LoadConfig(ctx context.Context, region string) (aws.Config, error) {
-	return config.LoadDefaultConfig(ctx, config.WithRegion(region))
-}
-
-type WorkMailClient interface {
-	DeleteOrganization(ctx context.Context, params *workmail.DeleteOrganizationInput, optFns ...func(*workmail.Options)) (*workmail.DeleteOrganizationOutput, error)
+type DefaultAWSConfigLoader struct {
+	// Profile of the shared config, the default credentials are used when it is empty.
+	Profile string
 }
 
-type EC2Client interface {
-	DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, opts ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error)
-	DeleteNetworkInterface(ctx context.Context, params *ec2.DeleteNetworkInterfaceInput, opts ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error)
-}
+// LoadConfig loads the AWS configuration using the AWS SDK. A Profile that is set but missing is an error,
+// falling back to the default credentials could run the helpers against another account.
+func (d *DefaultAWSConfigLoader) LoadConfig(ctx context.Context, region string) (aws.Config, error) {
+	if d.Profile == "" {
+		return config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	}
 
-func LoadEC2Client(region string) (*ec2.Client, error) {
-	// Load AWS configuration
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(region))
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region), config.WithSharedConfigProfile(d.Profile))
 	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		return aws.Config{}, fmt.Errorf("profile %s: %w", d.Profile, err)
 	}
 
-	// Return the EC2 client
-	return ec2.NewFromConfig(cfg), nil
+	return cfg, nil
 }
 
-func RemoveENI(t *testing.T, vpcID string, svc EC2Client) (int32, error) {
-	var counter int32
+// EnvAWSConfigLoader loads the credentials of a command that gets Env on top of the process environment,
+// like terragrunt with the EnvVars of terraform.Options. Access keys win over AWS_PROFILE, as they do there.
+type EnvAWSConfigLoader struct {
+	Env map[string]string
+}
 
-	logger.Log(t, "Remove unused ENIs in VPC Id:", vpcID)
+// LoadConfig loads the AWS configuration the command would use for region.
+func (e *EnvAWSConfigLoader) LoadConfig(ctx context.Context, region string) (aws.Config, error) {
+	opts := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if file := e.lookup("AWS_CONFIG_FILE"); file != "" {
+		opts = append(opts, config.WithSharedConfigFiles([]string{file}))
+	}
+	if file := e.lookup("AWS_SHARED_CREDENTIALS_FILE"); file != "" {
+		opts = append(opts, config.WithSharedCredentialsFiles([]string{file}))
+	}
+	if key := e.lookup("AWS_ACCESS_KEY_ID"); key != "" {
+		opts = append(opts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			key, e.lookup("AWS_SECRET_ACCESS_KEY"), e.lookup("AWS_SESSION_TOKEN"))))
+	} else if profile := e.lookup("AWS_PROFILE"); profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
+	}
 
-	describeInput := &ec2.DescribeNetworkInterfacesInput{}
-	result, err := svc.DescribeNetworkInterfaces(context.TODO(), describeInput)
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
 	if err != nil {
-		return 0, fmt.Errorf("error describing network interfaces: %w", err)
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	// Delete each unused network interface
-	for _, networkInterface := range result.NetworkInterfaces {
-		if networkInterface.Attachment == nil || networkInterface.Attachment.InstanceId == nil && networkInterface.VpcId == &vpcID {
-			// ENI is not attached to any instance, safe to delete
-			deleteInput := &ec2.DeleteNetworkInterfaceInput{
-				NetworkInterfaceId: networkInterface.NetworkInterfaceId,
-			}
-
-			_, deleteErr := svc.DeleteNetworkInterface(context.TODO(), deleteInput)
-			if deleteErr != nil {
-				logger.Log(t, "Error deleting ENI", deleteErr) // Log the error for debugging purposes("error deleting ENI: %v", deleteErr).
-			} else {
-				counter++
-			}
-		}
+	return cfg, nil
+}
+
+// lookup returns key of Env, of the process environment when Env does not set it.
+func (e *EnvAWSConfigLoader) lookup(key string) string {
+	if value, ok := e.Env[key]; ok {
+		return value
 	}
 
-	logger.Log(t, "Number of ENIs deleted:", counter)
+	return os.Getenv(key)
+}
 
-	return counter, nil
+type WorkMailClient interface {
+	DeleteOrganization(ctx context.Context, params *workmail.DeleteOrganizationInput, optFns ...func(*workmail.Options)) (*workmail.DeleteOrganizationOutput, error)
 }
 
-func DeleteWorkMailOrganization(t *testing.T, orgID string, client WorkMailClient) error {
-	// Load the default AWS configuration.
+type EC2Client interface {
+	DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, opts ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error)
+	DeleteNetworkInterface(ctx context.Context, params *ec2.DeleteNetworkInterfaceInput, opts ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error)
+	DetachNetworkInterface(ctx context.Context, params *ec2.DetachNetworkInterfaceInput, opts ...func(*ec2.Options)) (*ec2.DetachNetworkInterfaceOutput, error)
+}
 
-	logger.Log(t, "Remove Wokrmail ORGId:", orgID)
+// LoadEC2Client returns an EC2 client for region once STS confirmed the account of cfg, see ClientFactory.EC2.
+func LoadEC2Client(cfg core.RunTime, region string) (*ec2.Client, error) {
+	factory, err := NewClientFactoryFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	loader := &DefaultAWSConfigLoader{}
-	_, err := loader.LoadConfig(context.TODO(), parameters.AWSRegion)
+	return factory.EC2(region)
+}
 
-	if err != nil {
-		return fmt.Errorf("AWS Auth error %w", err)
+// DeleteWorkMailOrganization deletes the organization once guard passed, see ClientFactory.Guard.
+func DeleteWorkMailOrganization(t *testing.T, guard *AccountGuard, orgID string, client WorkMailClient) error {
+	if err := checkGuard(t, guard); err != nil {
+		return err
 	}
 
+	logger.Log(t, "Remove Wokrmail ORGId:", orgID)
+
 	// Create the input parameters for the DeleteOrganization API call.
 	input := &workmail.DeleteOrganizationInput{
 		OrganizationId: aws.String(orgID),
 	}
 
 	// Call the DeleteOrganization API.
-	_, err = client.DeleteOrganization(context.TODO(), input)
+	_, err := client.DeleteOrganization(context.TODO(), input)
 	if err != nil {
 		return fmt.Errorf("failed to delete WorkMail organization: %w", err)
 	}
@@ -29,6 +29,7 @@ type MockWorkMailClient struct {
 type MockEC2Client struct {
 	DescribeNetworkInterfacesFunc func(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, opts ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error)
 	DeleteNetworkInterfaceFunc    func(ctx context.Context, params *ec2.DeleteNetworkInterfaceInput, opts ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error)
+	DetachNetworkInterfaceFunc    func(ctx context.Context, params *ec2.DetachNetworkInterfaceInput, opts ...func(*ec2.Options)) (*ec2.DetachNetworkInterfaceOutput, error)
 }
 
 func (m *MockWorkMailClient) DeleteOrganization(ctx context.Context, params *workmail.DeleteOrganizationInput, optFns ...func(*workmail.Options)) (*workmail.DeleteOrganizationOutput, error) {
@@ -43,6 +44,10 @@ func (m *MockEC2Client) DeleteNetworkInterface(ctx context.Context, params *ec2.
 	return m.DeleteNetworkInterfaceFunc(ctx, params, opts...)
 }
 
+func (m *MockEC2Client) DetachNetworkInterface(ctx context.Context, params *ec2.DetachNetworkInterfaceInput, opts ...func(*ec2.Options)) (*ec2.DetachNetworkInterfaceOutput, error) {
+	return m.DetachNetworkInterfaceFunc(ctx, params, opts...)
+}
+
 // Unit test for DeleteWorkMailOrganization.
 func TestMockDeleteWorkMailOrganization(t *testing.T) {
 	t.Parallel()
@@ -73,7 +78,7 @@ func TestMockDeleteWorkMailOrganization(t *testing.T) {
 	}
 
 	// Call the function under test.
-	err = awsutils.DeleteWorkMailOrganization(t, "test-org-id", mockClient)
+	err = awsutils.DeleteWorkMailOrganization(t, passingGuard(), "test-org-id", mockClient)
 
 	// Assert no error.
 	assert.NoError(t, err)
@@ -107,7 +112,7 @@ func TestMockFailureDeleteWorkMailOrganization(t *testing.T) {
 	}
 
 	// Call the function under test.
-	err = awsutils.DeleteWorkMailOrganization(t, "test-org-id", mockClient)
+	err = awsutils.DeleteWorkMailOrganization(t, passingGuard(), "test-org-id", mockClient)
 
 	// Assert  error.
 	assert.ErrorContainsf(t, err, "failed to delete WorkMail organization", err.Error())
@@ -151,7 +156,7 @@ func TestMockRemoveENI(t *testing.T) {
 		},
 	}
 
-	counter, err := awsutils.RemoveENI(t, "vpc-123456", mockClient)
+	counter, err := awsutils.RemoveENI(t, passingGuard(), "vpc-123456", mockClient)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -190,7 +195,7 @@ func TestMockFailiureRemoveENI(t *testing.T) {
 		},
 	}
 
-	counter, err := awsutils.RemoveENI(t, "vpc-123456", mockClient)
+	counter, err := awsutils.RemoveENI(t, passingGuard(), "vpc-123456", mockClient)
 	if err == nil {
 		t.Fatalf("expected error, got %v", err)
 	}
@@ -0,0 +1,149 @@
+package awsutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+// LambdaENIDescription matches the description of the ENIs Lambda creates for VPC functions.
+const LambdaENIDescription = `^AWS Lambda VPC ENI`
+
+// ENICleanupOptions selects the available ENIs of a VPC to delete.
+type ENICleanupOptions struct {
+	// VPCID is required, ENIs are never looked up region wide.
+	VPCID string
+	// Tags only keeps ENIs carrying every tag, filtered server side.
+	Tags map[string]string
+	// RequesterID only keeps ENIs created by this requester, wildcards such as *:awslambda_* are allowed.
+	RequesterID string
+	// Description is a regular expression the ENI description must match, see LambdaENIDescription.
+	Description string
+	// DryRun only returns the candidates.
+	DryRun bool
+}
+
+// ENICleanup lists the ENI IDs selected for deletion and those actually deleted.
+type ENICleanup struct {
+	Candidates []string
+	Deleted    []string
+}
+
+// filters narrows DescribeNetworkInterfaces to the ENIs of the VPC, only the unattached ones if available is set.
+func (o ENICleanupOptions) filters(available bool) []types.Filter {
+	filters := []types.Filter{{Name: aws.String("vpc-id"), Values: []string{o.VPCID}}}
+	if available {
+		filters = append(filters, types.Filter{Name: aws.String("status"), Values: []string{string(types.NetworkInterfaceStatusAvailable)}})
+	}
+	if o.RequesterID != "" {
+		filters = append(filters, types.Filter{Name: aws.String("requester-id"), Values: []string{o.RequesterID}})
+	}
+
+	keys := make([]string, 0, len(o.Tags))
+	for key := range o.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		filters = append(filters, types.Filter{Name: aws.String("tag:" + key), Values: []string{o.Tags[key]}})
+	}
+
+	return filters
+}
+
+// FindENIs returns the available ENIs matching opts.
+func FindENIs(svc EC2Client, opts ENICleanupOptions) ([]types.NetworkInterface, error) {
+	return describeENIs(svc, opts, true)
+}
+
+func describeENIs(svc EC2Client, opts ENICleanupOptions, available bool) ([]types.NetworkInterface, error) {
+	if opts.VPCID == "" {
+		return nil, ErrNoVPC
+	}
+
+	var description *regexp.Regexp
+	if opts.Description != "" {
+		var err error
+		if description, err = regexp.Compile(opts.Description); err != nil {
+			return nil, fmt.Errorf("invalid description pattern: %w", err)
+		}
+	}
+
+	var found []types.NetworkInterface
+	paginator := ec2.NewDescribeNetworkInterfacesPaginator(svc, &ec2.DescribeNetworkInterfacesInput{Filters: opts.filters(available)})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("error describing network interfaces: %w", err)
+		}
+
+		for _, networkInterface := range page.NetworkInterfaces {
+			// The vpc-id filter already did this, but ENIs of another VPC must never be touched.
+			if aws.ToString(networkInterface.VpcId) != opts.VPCID {
+				continue
+			}
+			if description != nil && !description.MatchString(aws.ToString(networkInterface.Description)) {
+				continue
+			}
+			found = append(found, networkInterface)
+		}
+	}
+
+	return found, nil
+}
+
+// CleanENIs deletes the available ENIs matching opts once guard passed, every failed deletion is part of
+// the returned error.
+func CleanENIs(t *testing.T, guard *AccountGuard, svc EC2Client, opts ENICleanupOptions) (ENICleanup, error) {
+	var cleanup ENICleanup
+	if err := checkGuard(t, guard); err != nil {
+		return cleanup, err
+	}
+
+	logger.Log(t, "Remove unused ENIs in VPC Id:", opts.VPCID)
+
+	found, err := FindENIs(svc, opts)
+	if err != nil {
+		return cleanup, err
+	}
+	for _, networkInterface := range found {
+		cleanup.Candidates = append(cleanup.Candidates, aws.ToString(networkInterface.NetworkInterfaceId))
+	}
+
+	if opts.DryRun {
+		logger.Log(t, "Dry run, ENIs that would be deleted:", cleanup.Candidates)
+
+		return cleanup, nil
+	}
+
+	var errs []error
+	for _, id := range cleanup.Candidates {
+		if err := deleteENI(svc, id); err != nil {
+			errs = append(errs, fmt.Errorf("error deleting ENI %s: %w", id, err))
+
+			continue
+		}
+		cleanup.Deleted = append(cleanup.Deleted, id)
+	}
+
+	logger.Log(t, "Number of ENIs deleted:", len(cleanup.Deleted))
+
+	return cleanup, errors.Join(errs...)
+}
+
+// RemoveENI deletes the available ENIs of the VPC and returns how many were deleted.
+func RemoveENI(t *testing.T, guard *AccountGuard, vpcID string, svc EC2Client) (int32, error) {
+	cleanup, err := CleanENIs(t, guard, svc, ENICleanupOptions{VPCID: vpcID})
+
+	return int32(len(cleanup.Deleted)), err
+}
+
+var ErrNoVPC = errors.New("a VPC ID is required to remove ENIs")
@@ -0,0 +1,258 @@
+package awsutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GoGstickGo/terratest-helpers/pkg/testutils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+const (
+	defaultENITimeout  = 10 * time.Minute
+	defaultENIInterval = 15 * time.Second
+)
+
+// Results of an ENI in an ENIReport.
+const (
+	ENIDeleted = "deleted"
+	// ENIReleased ENIs disappeared while waiting, the owning service deleted them.
+	ENIReleased = "released"
+	ENIInUse    = "in-use"
+	ENIFailed   = "failed"
+	// ENICandidate ENIs were found by a dry run.
+	ENICandidate = "candidate"
+)
+
+// ENIReleaseOptions selects the ENIs of a VPC to wait for and delete, they do not need to be available yet.
+type ENIReleaseOptions struct {
+	ENICleanupOptions
+	// Timeout bounds the wait for in-use ENIs, it defaults to 10 minutes.
+	Timeout time.Duration
+	// Interval is the wait between two checks, it defaults to 15 seconds.
+	Interval time.Duration
+	// Detach detaches in-use ENIs where permitted, it requires Tags or RequesterID. ENIs managed by a service
+	// such as Lambda or EKS and the primary ENI of an instance cannot be detached, they are only waited for.
+	Detach bool
+}
+
+// ENIOutcome is what happened to one ENI.
+type ENIOutcome struct {
+	ID       string
+	Result   string
+	Detached bool
+	// Checks counts how often the ENI was seen.
+	Checks int
+	Err    error
+}
+
+// ENIReport lists the outcome of every ENI in the order they were found.
+type ENIReport []ENIOutcome
+
+// Deleted returns the IDs of the deleted ENIs.
+func (r ENIReport) Deleted() []string {
+	var deleted []string
+	for _, outcome := range r {
+		if outcome.Result == ENIDeleted {
+			deleted = append(deleted, outcome.ID)
+		}
+	}
+
+	return deleted
+}
+
+// Err joins the errors of the ENIs that are still there, nil if every ENI is gone.
+func (r ENIReport) Err() error {
+	var errs []error
+	for _, outcome := range r {
+		if outcome.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", outcome.ID, outcome.Err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ReleaseENIs waits until the ENIs matching opts become available, detaching them where permitted, and deletes
+// each as soon as it is. ENIs still in use after opts.Timeout are reported with ErrENIInUse.
+// It refuses to run with ErrNoENIFilter unless opts narrows the VPC by Tags, RequesterID or Description,
+// and to detach unless the ENIs are matched by Tags or RequesterID. Nothing is touched unless guard passed.
+func ReleaseENIs(t *testing.T, guard *AccountGuard, svc EC2Client, sleeper testutils.Sleeper, opts ENIReleaseOptions) (ENIReport, error) {
+	opts, err := releaseOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGuard(t, guard); err != nil {
+		return nil, err
+	}
+
+	logger.Log(t, "Release ENIs in VPC Id:", opts.VPCID)
+
+	pending, err := describeENIs(svc, opts.ENICleanupOptions, false)
+	if err != nil {
+		return nil, err
+	}
+
+	report := make(ENIReport, len(pending))
+	index := map[string]int{}
+	for i, networkInterface := range pending {
+		report[i].ID = aws.ToString(networkInterface.NetworkInterfaceId)
+		index[report[i].ID] = i
+	}
+
+	if opts.DryRun {
+		for i := range report {
+			report[i].Result = ENICandidate
+		}
+		logger.Log(t, "Dry run, ENIs that would be released:", len(report))
+
+		return report, nil
+	}
+
+	pending, err = waitForENIs(t, svc, sleeper, opts, pending, report, index)
+	if err != nil {
+		return report, err
+	}
+	markInUse(opts, pending, report, index)
+
+	logger.Log(t, "Number of ENIs deleted:", len(report.Deleted()))
+
+	return report, report.Err()
+}
+
+// releaseOptions checks the ENI filter of opts and fills in the default timeout and interval.
+func releaseOptions(opts ENIReleaseOptions) (ENIReleaseOptions, error) {
+	if len(opts.Tags) == 0 && opts.RequesterID == "" && opts.Description == "" {
+		return opts, ErrNoENIFilter
+	}
+	if opts.Detach && len(opts.Tags) == 0 && opts.RequesterID == "" {
+		return opts, fmt.Errorf("%w: detaching requires Tags or RequesterID", ErrNoENIFilter)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultENITimeout
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultENIInterval
+	}
+
+	return opts, nil
+}
+
+// waitForENIs releases the pending ENIs every opts.Interval until none is left or opts.Timeout has passed,
+// it returns the ENIs still there.
+func waitForENIs(t *testing.T, svc EC2Client, sleeper testutils.Sleeper, opts ENIReleaseOptions,
+	pending []types.NetworkInterface, report ENIReport, index map[string]int) ([]types.NetworkInterface, error) {
+	for waited := time.Duration(0); ; waited += opts.Interval {
+		pending = releaseStep(svc, opts, pending, report, index)
+		if len(pending) == 0 || waited >= opts.Timeout {
+			return pending, nil
+		}
+
+		logger.Log(t, len(pending), "ENIs still in use, next check in", opts.Interval)
+		sleeper.Sleep(opts.Interval)
+
+		current, err := describeENIs(svc, opts.ENICleanupOptions, false)
+		if err != nil {
+			return pending, err
+		}
+		pending = refreshENIs(pending, current, report, index)
+	}
+}
+
+// markInUse reports the ENIs left after the timeout with ErrENIInUse.
+func markInUse(opts ENIReleaseOptions, pending []types.NetworkInterface, report ENIReport, index map[string]int) {
+	for _, networkInterface := range pending {
+		outcome := &report[index[aws.ToString(networkInterface.NetworkInterfaceId)]]
+		outcome.Result = ENIInUse
+		if networkInterface.Status == types.NetworkInterfaceStatusAvailable {
+			outcome.Result = ENIFailed
+		}
+		if outcome.Err != nil {
+			outcome.Err = fmt.Errorf("%w after %s: %w", ErrENIInUse, opts.Timeout, outcome.Err)
+		} else {
+			outcome.Err = fmt.Errorf("%w after %s, status %s", ErrENIInUse, opts.Timeout, networkInterface.Status)
+		}
+	}
+}
+
+// releaseStep deletes the available ENIs, detaches the others where permitted and returns those left.
+func releaseStep(svc EC2Client, opts ENIReleaseOptions, pending []types.NetworkInterface, report ENIReport, index map[string]int) []types.NetworkInterface {
+	var left []types.NetworkInterface
+	for _, networkInterface := range pending {
+		outcome := &report[index[aws.ToString(networkInterface.NetworkInterfaceId)]]
+		outcome.Checks++
+
+		switch {
+		case networkInterface.Status == types.NetworkInterfaceStatusAvailable:
+			if err := deleteENI(svc, outcome.ID); err != nil {
+				outcome.Err = fmt.Errorf("error deleting ENI: %w", err)
+				left = append(left, networkInterface)
+
+				continue
+			}
+			outcome.Result = ENIDeleted
+			outcome.Err = nil
+
+			continue
+		case opts.Detach && !outcome.Detached && outcome.Err == nil && detachable(networkInterface):
+			detachENI(svc, networkInterface, outcome)
+		}
+		left = append(left, networkInterface)
+	}
+
+	return left
+}
+
+// detachENI detaches the ENI once, a failure is not retried as the ENI may still be released by its owner.
+func detachENI(svc EC2Client, networkInterface types.NetworkInterface, outcome *ENIOutcome) {
+	_, err := svc.DetachNetworkInterface(context.TODO(), &ec2.DetachNetworkInterfaceInput{
+		AttachmentId: networkInterface.Attachment.AttachmentId,
+	})
+	if err != nil {
+		outcome.Err = fmt.Errorf("error detaching ENI: %w", err)
+
+		return
+	}
+	outcome.Detached = true
+}
+
+// refreshENIs replaces the pending ENIs by their current state, ENIs that are gone are marked released.
+func refreshENIs(pending, current []types.NetworkInterface, report ENIReport, index map[string]int) []types.NetworkInterface {
+	byID := map[string]types.NetworkInterface{}
+	for _, networkInterface := range current {
+		byID[aws.ToString(networkInterface.NetworkInterfaceId)] = networkInterface
+	}
+
+	var left []types.NetworkInterface
+	for _, networkInterface := range pending {
+		id := aws.ToString(networkInterface.NetworkInterfaceId)
+		if latest, ok := byID[id]; ok {
+			left = append(left, latest)
+
+			continue
+		}
+		report[index[id]].Result = ENIReleased
+		report[index[id]].Err = nil
+	}
+
+	return left
+}
+
+// detachable reports whether the ENI is a secondary ENI attached by us, AWS refuses to detach the others.
+func detachable(networkInterface types.NetworkInterface) bool {
+	attachment := networkInterface.Attachment
+
+	return attachment != nil && attachment.AttachmentId != nil &&
+		!aws.ToBool(networkInterface.RequesterManaged) && aws.ToInt32(attachment.DeviceIndex) != 0
+}
+
+var (
+	ErrENIInUse    = errors.New("ENI still in use")
+	ErrNoENIFilter = errors.New("ENIs to release must be selected by Tags, RequesterID or Description")
+)
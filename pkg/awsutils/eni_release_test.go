@@ -0,0 +1,162 @@
+package awsutils_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GoGstickGo/terratest-helpers/pkg/awsutils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockSleeper records the sleeps instead of sleeping.
+type MockSleeper struct {
+	slept []time.Duration
+}
+
+func (m *MockSleeper) Sleep(duration time.Duration) {
+	m.slept = append(m.slept, duration)
+}
+
+func (m *MockSleeper) SleepUntil(duration time.Duration, _ <-chan struct{}) bool {
+	m.slept = append(m.slept, duration)
+
+	return false
+}
+
+func inUseENI(id string, deviceIndex int32, requesterManaged bool) types.NetworkInterface {
+	networkInterface := availableENI(id, "vpc-123456", "")
+	networkInterface.Status = types.NetworkInterfaceStatusInUse
+	networkInterface.RequesterManaged = aws.Bool(requesterManaged)
+	networkInterface.Attachment = &types.NetworkInterfaceAttachment{
+		AttachmentId: aws.String("attach-" + id),
+		DeviceIndex:  aws.Int32(deviceIndex),
+	}
+
+	return networkInterface
+}
+
+func TestMockReleaseENIs(t *testing.T) {
+	t.Parallel()
+
+	// Each describe returns the next state, the last one is kept.
+	states := [][]types.NetworkInterface{
+		{inUseENI("eni-lambda", 1, true), inUseENI("eni-secondary", 1, false), inUseENI("eni-eks", 0, false)},
+		{inUseENI("eni-lambda", 1, true), availableENI("eni-secondary", "vpc-123456", "")},
+		{availableENI("eni-lambda", "vpc-123456", "")},
+	}
+	describes := 0
+	var detached, deleted []string
+	mockClient := &MockEC2Client{
+		DescribeNetworkInterfacesFunc: func(_ context.Context, params *ec2.DescribeNetworkInterfacesInput, _ ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+			assert.NotContains(t, filterValues(params.Filters), "status")
+			state := states[min(describes, len(states)-1)]
+			describes++
+
+			return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: state}, nil
+		},
+		DetachNetworkInterfaceFunc: func(_ context.Context, params *ec2.DetachNetworkInterfaceInput, _ ...func(*ec2.Options)) (*ec2.DetachNetworkInterfaceOutput, error) {
+			detached = append(detached, aws.ToString(params.AttachmentId))
+
+			return &ec2.DetachNetworkInterfaceOutput{}, nil
+		},
+		DeleteNetworkInterfaceFunc: func(_ context.Context, params *ec2.DeleteNetworkInterfaceInput, _ ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error) {
+			deleted = append(deleted, aws.ToString(params.NetworkInterfaceId))
+
+			return &ec2.DeleteNetworkInterfaceOutput{}, nil
+		},
+	}
+	sleeper := &MockSleeper{}
+
+	report, err := awsutils.ReleaseENIs(t, passingGuard(), mockClient, sleeper, awsutils.ENIReleaseOptions{
+		ENICleanupOptions: awsutils.ENICleanupOptions{VPCID: "vpc-123456", Tags: map[string]string{"tt_run_id": "run-1"}},
+		Interval:          5 * time.Second,
+		Detach:            true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"attach-eni-secondary"}, detached, "service managed and primary ENIs are not detached")
+	assert.Equal(t, []string{"eni-secondary", "eni-lambda"}, deleted)
+	assert.Equal(t, []time.Duration{5 * time.Second, 5 * time.Second}, sleeper.slept)
+	assert.Equal(t, awsutils.ENIReport{
+		{ID: "eni-lambda", Result: awsutils.ENIDeleted, Checks: 3},
+		{ID: "eni-secondary", Result: awsutils.ENIDeleted, Detached: true, Checks: 2},
+		{ID: "eni-eks", Result: awsutils.ENIReleased, Checks: 1},
+	}, report)
+}
+
+func TestMockReleaseENIs_Timeout(t *testing.T) {
+	t.Parallel()
+
+	mockClient := &MockEC2Client{
+		DescribeNetworkInterfacesFunc: func(_ context.Context, _ *ec2.DescribeNetworkInterfacesInput, _ ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+			return &ec2.DescribeNetworkInterfacesOutput{
+				NetworkInterfaces: []types.NetworkInterface{inUseENI("eni-lambda", 1, true), availableENI("eni-1", "vpc-123456", "")},
+			}, nil
+		},
+		DeleteNetworkInterfaceFunc: func(_ context.Context, _ *ec2.DeleteNetworkInterfaceInput, _ ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error) {
+			return &ec2.DeleteNetworkInterfaceOutput{}, nil
+		},
+	}
+	sleeper := &MockSleeper{}
+
+	report, err := awsutils.ReleaseENIs(t, passingGuard(), mockClient, sleeper, awsutils.ENIReleaseOptions{
+		ENICleanupOptions: awsutils.ENICleanupOptions{VPCID: "vpc-123456", RequesterID: "*:awslambda_*"},
+		Timeout:           30 * time.Second,
+		Interval:          10 * time.Second,
+	})
+	require.ErrorIs(t, err, awsutils.ErrENIInUse)
+	assert.ErrorContains(t, err, "eni-lambda")
+
+	assert.Len(t, sleeper.slept, 3)
+	assert.Equal(t, awsutils.ENIInUse, report[0].Result)
+	assert.Equal(t, 4, report[0].Checks)
+	assert.Equal(t, []string{"eni-1"}, report.Deleted())
+}
+
+func TestMockReleaseENIs_DryRun(t *testing.T) {
+	t.Parallel()
+
+	mockClient := &MockEC2Client{
+		DescribeNetworkInterfacesFunc: func(_ context.Context, _ *ec2.DescribeNetworkInterfacesInput, _ ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+			return &ec2.DescribeNetworkInterfacesOutput{
+				NetworkInterfaces: []types.NetworkInterface{inUseENI("eni-1", 1, false)},
+			}, nil
+		},
+	}
+	sleeper := &MockSleeper{}
+
+	report, err := awsutils.ReleaseENIs(t, passingGuard(), mockClient, sleeper, awsutils.ENIReleaseOptions{
+		ENICleanupOptions: awsutils.ENICleanupOptions{VPCID: "vpc-123456", Tags: map[string]string{"tt_run_id": "run-1"}, DryRun: true},
+		Detach:            true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, awsutils.ENIReport{{ID: "eni-1", Result: awsutils.ENICandidate}}, report)
+	assert.Empty(t, sleeper.slept)
+}
+
+func TestMockReleaseENIs_NoFilter(t *testing.T) {
+	t.Parallel()
+
+	// The client has no functions, any describe, detach or delete would panic.
+	mockClient := &MockEC2Client{}
+	sleeper := &MockSleeper{}
+
+	_, err := awsutils.ReleaseENIs(t, passingGuard(), mockClient, sleeper, awsutils.ENIReleaseOptions{
+		ENICleanupOptions: awsutils.ENICleanupOptions{VPCID: "vpc-123456"},
+	})
+	require.ErrorIs(t, err, awsutils.ErrNoENIFilter)
+
+	// A description alone may select ENIs but not detach them.
+	_, err = awsutils.ReleaseENIs(t, passingGuard(), mockClient, sleeper, awsutils.ENIReleaseOptions{
+		ENICleanupOptions: awsutils.ENICleanupOptions{VPCID: "vpc-123456", Description: awsutils.LambdaENIDescription},
+		Detach:            true,
+	})
+	require.ErrorIs(t, err, awsutils.ErrNoENIFilter)
+	assert.ErrorContains(t, err, "detaching requires Tags or RequesterID")
+	assert.Empty(t, sleeper.slept)
+}
@@ -0,0 +1,142 @@
+package awsutils_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/pkg/awsutils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func availableENI(id, vpcID, description string) types.NetworkInterface {
+	return types.NetworkInterface{
+		NetworkInterfaceId: aws.String(id),
+		VpcId:              aws.String(vpcID),
+		Description:        aws.String(description),
+		Status:             types.NetworkInterfaceStatusAvailable,
+	}
+}
+
+func filterValues(filters []types.Filter) map[string][]string {
+	values := map[string][]string{}
+	for _, filter := range filters {
+		values[aws.ToString(filter.Name)] = filter.Values
+	}
+
+	return values
+}
+
+func TestMockCleanENIs_FiltersAndPaginates(t *testing.T) {
+	t.Parallel()
+
+	var filters map[string][]string
+	var deleted []string
+	mockClient := &MockEC2Client{
+		DescribeNetworkInterfacesFunc: func(_ context.Context, params *ec2.DescribeNetworkInterfacesInput, _ ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+			filters = filterValues(params.Filters)
+			if params.NextToken == nil {
+				return &ec2.DescribeNetworkInterfacesOutput{
+					NetworkInterfaces: []types.NetworkInterface{availableENI("eni-1", "vpc-123456", "AWS Lambda VPC ENI-test")},
+					NextToken:         aws.String("page-2"),
+				}, nil
+			}
+
+			return &ec2.DescribeNetworkInterfacesOutput{
+				NetworkInterfaces: []types.NetworkInterface{
+					availableENI("eni-2", "vpc-123456", "manual"),
+					availableENI("eni-3", "vpc-other", "AWS Lambda VPC ENI-other"),
+					availableENI("eni-4", "vpc-123456", "AWS Lambda VPC ENI-test2"),
+				},
+			}, nil
+		},
+		DeleteNetworkInterfaceFunc: func(_ context.Context, params *ec2.DeleteNetworkInterfaceInput, _ ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error) {
+			deleted = append(deleted, aws.ToString(params.NetworkInterfaceId))
+
+			return &ec2.DeleteNetworkInterfaceOutput{}, nil
+		},
+	}
+
+	cleanup, err := awsutils.CleanENIs(t, passingGuard(), mockClient, awsutils.ENICleanupOptions{
+		VPCID:       "vpc-123456",
+		Tags:        map[string]string{"tt_run_id": "run-1"},
+		RequesterID: "*:awslambda_*",
+		Description: awsutils.LambdaENIDescription,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"vpc-id":        {"vpc-123456"},
+		"status":        {"available"},
+		"requester-id":  {"*:awslambda_*"},
+		"tag:tt_run_id": {"run-1"},
+	}, filters)
+	assert.Equal(t, []string{"eni-1", "eni-4"}, cleanup.Candidates)
+	assert.Equal(t, []string{"eni-1", "eni-4"}, cleanup.Deleted)
+	assert.Equal(t, []string{"eni-1", "eni-4"}, deleted)
+}
+
+func TestMockCleanENIs_DryRun(t *testing.T) {
+	t.Parallel()
+
+	mockClient := &MockEC2Client{
+		DescribeNetworkInterfacesFunc: func(_ context.Context, _ *ec2.DescribeNetworkInterfacesInput, _ ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+			return &ec2.DescribeNetworkInterfacesOutput{
+				NetworkInterfaces: []types.NetworkInterface{availableENI("eni-1", "vpc-123456", "")},
+			}, nil
+		},
+		DeleteNetworkInterfaceFunc: func(_ context.Context, _ *ec2.DeleteNetworkInterfaceInput, _ ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error) {
+			t.Fatal("dry run must not delete")
+
+			return nil, nil
+		},
+	}
+
+	cleanup, err := awsutils.CleanENIs(t, passingGuard(), mockClient, awsutils.ENICleanupOptions{VPCID: "vpc-123456", DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eni-1"}, cleanup.Candidates)
+	assert.Empty(t, cleanup.Deleted)
+}
+
+func TestMockCleanENIs_AggregatesFailures(t *testing.T) {
+	t.Parallel()
+
+	mockClient := &MockEC2Client{
+		DescribeNetworkInterfacesFunc: func(_ context.Context, _ *ec2.DescribeNetworkInterfacesInput, _ ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+			return &ec2.DescribeNetworkInterfacesOutput{
+				NetworkInterfaces: []types.NetworkInterface{
+					availableENI("eni-1", "vpc-123456", ""),
+					availableENI("eni-2", "vpc-123456", ""),
+					availableENI("eni-3", "vpc-123456", ""),
+				},
+			}, nil
+		},
+		DeleteNetworkInterfaceFunc: func(_ context.Context, params *ec2.DeleteNetworkInterfaceInput, _ ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error) {
+			if aws.ToString(params.NetworkInterfaceId) == "eni-2" {
+				return &ec2.DeleteNetworkInterfaceOutput{}, nil
+			}
+
+			return nil, errors.New("InvalidNetworkInterface.InUse")
+		},
+	}
+
+	counter, err := awsutils.RemoveENI(t, passingGuard(), "vpc-123456", mockClient)
+	require.Error(t, err)
+	assert.Equal(t, int32(1), counter)
+	assert.ErrorContains(t, err, "eni-1")
+	assert.ErrorContains(t, err, "eni-3")
+}
+
+func TestMockCleanENIs_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := awsutils.CleanENIs(t, passingGuard(), &MockEC2Client{}, awsutils.ENICleanupOptions{})
+	require.ErrorIs(t, err, awsutils.ErrNoVPC)
+
+	_, err = awsutils.CleanENIs(t, passingGuard(), &MockEC2Client{}, awsutils.ENICleanupOptions{VPCID: "vpc-123456", Description: "("})
+	assert.ErrorContains(t, err, "invalid description pattern")
+}
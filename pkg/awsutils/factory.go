@@ -0,0 +1,250 @@
+package awsutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/parameters"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/workmail"
+)
+
+type AWSConfigLoader interface {
+	LoadConfig(ctx context.Context, region string) (aws.Config, error)
+}
+
+type STSClient interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// Target is the account and region the AWS helpers operate on.
+type Target struct {
+	Region    string
+	AccountID string
+	Profile   string
+	// RoleARN is assumed when set.
+	RoleARN string
+	// VPCID is the VPC of the test stack.
+	VPCID string
+}
+
+// TargetFromConfig reads aws_region, account_id, vpc_id and aws_profile from the vars file, cfg.AWS overrides
+// them. Without either the region and VPC fall back to the parameters package, the profile to the default
+// credential chain like terragrunt's. The account stays empty, its placeholder in parameters is no real account
+// to verify against.
+func TargetFromConfig(cfg core.RunTime, fs core.FileSystem) (Target, error) {
+	locals, err := core.ReadVarsLocals(cfg, fs)
+	if err != nil {
+		return Target{}, err
+	}
+
+	target := Target{
+		Region:    firstNonEmpty(cfg.AWS.Region, locals["aws_region"], parameters.AWSRegion),
+		AccountID: firstNonEmpty(cfg.AWS.AccountID, locals["account_id"]),
+		Profile:   firstNonEmpty(cfg.AWS.Profile, locals["aws_profile"]),
+		RoleARN:   cfg.AWS.AssumeRole,
+		VPCID:     firstNonEmpty(cfg.AWS.VPCID, locals["vpc_id"], parameters.VPCId),
+	}
+	// A bare role name lives in the target account.
+	if target.RoleARN != "" && !strings.HasPrefix(target.RoleARN, "arn:") {
+		if target.AccountID == "" {
+			return Target{}, fmt.Errorf("%w: role %s needs account_id or TT_AWS_ACCOUNT_ID", ErrNoTargetAccount, target.RoleARN)
+		}
+		target.RoleARN = fmt.Sprintf("arn:aws:iam::%s:role/%s", target.AccountID, target.RoleARN)
+	}
+
+	return target, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// ClientFactory creates AWS clients for the target account, one config per region is cached.
+// Clients for destructive calls are only handed out once STS confirmed the target account. The helpers
+// such as CleanENIs and SweepTaggedResources check the account again with Guard, the passed check is
+// remembered and costs no second STS call.
+type ClientFactory struct {
+	Target Target
+	// Allowed lists the accounts clients may be created for, like allowed_account_ids of the provider.
+	// Empty allows every account.
+	Allowed []string
+	Loader  AWSConfigLoader
+	// SessionName names the assumed role session, it shows up in CloudTrail.
+	SessionName string
+	// NewSTS creates the client verifying the account, sts.NewFromConfig by default.
+	NewSTS func(cfg aws.Config) STSClient
+
+	mu      sync.Mutex
+	configs map[string]aws.Config
+	guard   *AccountGuard
+}
+
+// NewClientFactory creates a factory for target using the default credential chain and target.Profile.
+func NewClientFactory(target Target) *ClientFactory {
+	return &ClientFactory{
+		Target:      target,
+		Loader:      &DefaultAWSConfigLoader{Profile: target.Profile},
+		SessionName: "terratest-helpers",
+	}
+}
+
+// NewClientFactoryFromConfig creates a factory for the target read by TargetFromConfig, limited to
+// cfg.AllowedAccountIDs. It fails right away when the account of the vars file is not allowed.
+func NewClientFactoryFromConfig(cfg core.RunTime) (*ClientFactory, error) {
+	target, err := TargetFromConfig(cfg, core.OsFileSystem{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AWS target: %w", err)
+	}
+	if _, err := expectedAccount(cfg); err != nil {
+		return nil, err
+	}
+
+	factory := NewClientFactory(target)
+	factory.Allowed = cfg.AllowedAccountIDs
+	if cfg.RunID != "" {
+		factory.SessionName = "terratest-" + cfg.RunID
+	}
+
+	return factory, nil
+}
+
+// Config returns the AWS config for region, an empty region means the target region.
+func (f *ClientFactory) Config(region string) (aws.Config, error) {
+	if region == "" {
+		region = f.Target.Region
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if cfg, ok := f.configs[region]; ok {
+		return cfg, nil
+	}
+
+	cfg, err := f.Loader.LoadConfig(context.TODO(), region)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if f.Target.RoleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), f.Target.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = f.SessionName
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	if f.configs == nil {
+		f.configs = map[string]aws.Config{}
+	}
+	f.configs[region] = cfg
+
+	return cfg, nil
+}
+
+// BaseConfig returns the config of the profile for region without assuming Target.RoleARN.
+func (f *ClientFactory) BaseConfig(region string) (aws.Config, error) {
+	if region == "" {
+		region = f.Target.Region
+	}
+
+	cfg, err := f.Loader.LoadConfig(context.TODO(), region)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// VerifyAccount checks with STS that the credentials belong to the target account and, when Allowed is set,
+// to an allowed one. It is the AccountGuard of the target config, a success is remembered. Without a target
+// account and Allowed there is nothing to check against and every account passes.
+func (f *ClientFactory) VerifyAccount() error {
+	guard, err := f.Guard()
+	if err != nil {
+		return err
+	}
+	_, err = guard.verify()
+
+	return err
+}
+
+// Guard returns the AccountGuard of the target config, it expects the target account. Pass it to the
+// destructive helpers together with clients of the factory.
+func (f *ClientFactory) Guard() (*AccountGuard, error) {
+	cfg, err := f.Config("")
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.guard == nil {
+		newSTS := f.NewSTS
+		if newSTS == nil {
+			newSTS = func(cfg aws.Config) STSClient { return sts.NewFromConfig(cfg) }
+		}
+		f.guard = &AccountGuard{Allowed: f.Allowed, Expected: f.Target.AccountID, STS: newSTS(cfg)}
+	}
+
+	return f.guard, nil
+}
+
+// verifiedConfig returns the config for region once the account is verified.
+func (f *ClientFactory) verifiedConfig(region string) (aws.Config, error) {
+	if err := f.VerifyAccount(); err != nil {
+		return aws.Config{}, err
+	}
+
+	return f.Config(region)
+}
+
+// EC2 returns an EC2 client for region in the verified account.
+func (f *ClientFactory) EC2(region string) (*ec2.Client, error) {
+	cfg, err := f.verifiedConfig(region)
+	if err != nil {
+		return nil, err
+	}
+
+	return ec2.NewFromConfig(cfg), nil
+}
+
+// WorkMail returns a WorkMail client for region in the verified account.
+func (f *ClientFactory) WorkMail(region string) (*workmail.Client, error) {
+	cfg, err := f.verifiedConfig(region)
+	if err != nil {
+		return nil, err
+	}
+
+	return workmail.NewFromConfig(cfg), nil
+}
+
+// Sweep returns the sweeper clients for region in the verified account.
+func (f *ClientFactory) Sweep(region string) (SweepClients, error) {
+	cfg, err := f.verifiedConfig(region)
+	if err != nil {
+		return SweepClients{}, err
+	}
+	guard, err := f.Guard()
+	if err != nil {
+		return SweepClients{}, err
+	}
+	clients := newSweepClients(cfg)
+	clients.Guard = guard
+
+	return clients, nil
+}
+
+var ErrNoTargetAccount = errors.New("the AWS target has no account")
@@ -0,0 +1,274 @@
+package awsutils_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/awsutils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockSTSClient struct {
+	GetCallerIdentityFunc func(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+func (m *MockSTSClient) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	return m.GetCallerIdentityFunc(ctx, params, optFns...)
+}
+
+func awsConfig() aws.Config {
+	return aws.Config{Region: "us-east-1"}
+}
+
+// passingGuard returns a guard STS confirms the allowed account 111111111111 for.
+func passingGuard() *awsutils.AccountGuard {
+	calls := 0
+
+	return &awsutils.AccountGuard{Allowed: []string{"111111111111"}, STS: callerIdentity("111111111111", &calls)(awsConfig())}
+}
+
+// callerIdentity returns an STS client reporting account and counting the calls.
+func callerIdentity(account string, calls *int) func(aws.Config) awsutils.STSClient {
+	return func(aws.Config) awsutils.STSClient {
+		return &MockSTSClient{
+			GetCallerIdentityFunc: func(_ context.Context, _ *sts.GetCallerIdentityInput, _ ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+				*calls++
+
+				return &sts.GetCallerIdentityOutput{
+					Account: aws.String(account),
+					Arn:     aws.String("arn:aws:sts::" + account + ":assumed-role/terratest/ci"),
+				}, nil
+			},
+		}
+	}
+}
+
+func TestMockTargetFromConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "root_vars.hcl"), []byte(`locals {
+  account_id = "222222222222"
+  aws_region = "eu-west-1"
+  stage      = "aws-account"
+  vpc_id     = "vpc-0aaaaaaaaaaaaaaaa"
+}
+`), 0644))
+	cfg := core.RunTime{VarsFile: "root_vars.hcl", Paths: core.FolderPaths{TerragruntDir: dir}}
+
+	target, err := awsutils.TargetFromConfig(cfg, core.OsFileSystem{})
+	require.NoError(t, err)
+	// stage names the account, it is no profile, terragrunt uses the default credentials without aws_profile
+	assert.Equal(t, awsutils.Target{Region: "eu-west-1", AccountID: "222222222222", VPCID: "vpc-0aaaaaaaaaaaaaaaa"}, target)
+
+	cfg.AWS = core.AWSSettings{Region: "us-west-2", Profile: "ci", AssumeRole: "terratest", VPCID: "vpc-0bbbbbbbbbbbbbbbb"}
+	target, err = awsutils.TargetFromConfig(cfg, core.OsFileSystem{})
+	require.NoError(t, err)
+	assert.Equal(t, awsutils.Target{
+		Region:    "us-west-2",
+		AccountID: "222222222222",
+		Profile:   "ci",
+		RoleARN:   "arn:aws:iam::222222222222:role/terratest",
+		VPCID:     "vpc-0bbbbbbbbbbbbbbbb",
+	}, target)
+}
+
+func TestMockTargetFromConfig_NoAccount(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "root_vars.hcl"), []byte(`locals {
+  aws_region  = "eu-west-1"
+  aws_profile = "sandbox"
+}
+`), 0644))
+	cfg := core.RunTime{VarsFile: "root_vars.hcl", Paths: core.FolderPaths{TerragruntDir: dir}}
+
+	// The account is not taken from the parameters placeholder
+	target, err := awsutils.TargetFromConfig(cfg, core.OsFileSystem{})
+	require.NoError(t, err)
+	assert.Empty(t, target.AccountID)
+	assert.Equal(t, "sandbox", target.Profile)
+
+	cfg.AWS.AssumeRole = "terratest"
+	_, err = awsutils.TargetFromConfig(cfg, core.OsFileSystem{})
+	require.ErrorIs(t, err, awsutils.ErrNoTargetAccount)
+}
+
+func TestMockClientFactory_CachesPerRegion(t *testing.T) {
+	t.Parallel()
+
+	var regions []string
+	factory := &awsutils.ClientFactory{
+		Target: awsutils.Target{Region: "us-east-1", AccountID: "111111111111", RoleARN: "arn:aws:iam::111111111111:role/terratest"},
+		Loader: &MockAWSConfigLoader{
+			LoadConfigFunc: func(_ context.Context, region string) (aws.Config, error) {
+				regions = append(regions, region)
+
+				return aws.Config{Region: region}, nil
+			},
+		},
+	}
+
+	cfg, err := factory.Config("")
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", cfg.Region)
+	assert.IsType(t, &aws.CredentialsCache{}, cfg.Credentials, "the role is assumed")
+
+	_, err = factory.Config("us-east-1")
+	require.NoError(t, err)
+	_, err = factory.Config("eu-west-1")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"us-east-1", "eu-west-1"}, regions)
+}
+
+func TestMockClientFactory_VerifyAccount(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	factory := &awsutils.ClientFactory{
+		Target: awsutils.Target{Region: "us-east-1", AccountID: "111111111111"},
+		Loader: &MockAWSConfigLoader{
+			LoadConfigFunc: func(_ context.Context, region string) (aws.Config, error) {
+				return aws.Config{Region: region}, nil
+			},
+		},
+		NewSTS: callerIdentity("111111111111", &calls),
+	}
+
+	_, err := factory.EC2("")
+	require.NoError(t, err)
+	_, err = factory.WorkMail("eu-west-1")
+	require.NoError(t, err)
+	clients, err := factory.Sweep("")
+	require.NoError(t, err)
+	guard, err := factory.Guard()
+	require.NoError(t, err)
+	assert.Same(t, guard, clients.Guard)
+	require.NoError(t, guard.Check(t))
+	assert.Equal(t, 1, calls, "the account is verified once")
+}
+
+func TestMockClientFactory_AccountMismatch(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	factory := &awsutils.ClientFactory{
+		Target: awsutils.Target{Region: "us-east-1", AccountID: "111111111111"},
+		Loader: &MockAWSConfigLoader{
+			LoadConfigFunc: func(_ context.Context, region string) (aws.Config, error) {
+				return aws.Config{Region: region}, nil
+			},
+		},
+		NewSTS: callerIdentity("999999999999", &calls),
+	}
+
+	client, err := factory.EC2("")
+	require.ErrorIs(t, err, awsutils.ErrAccountMismatch)
+	assert.Nil(t, client)
+	assert.ErrorContains(t, err, "999999999999")
+
+	_, err = factory.WorkMail("")
+	require.ErrorIs(t, err, awsutils.ErrAccountMismatch)
+	assert.Equal(t, 2, calls, "a mismatch is checked again")
+}
+
+func TestMockClientFactory_AccountNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	factory := &awsutils.ClientFactory{
+		Target:  awsutils.Target{Region: "us-east-1", AccountID: "111111111111"},
+		Allowed: []string{"222222222222"},
+		Loader: &MockAWSConfigLoader{
+			LoadConfigFunc: func(_ context.Context, region string) (aws.Config, error) {
+				return aws.Config{Region: region}, nil
+			},
+		},
+		NewSTS: callerIdentity("111111111111", &calls),
+	}
+
+	_, err := factory.Sweep("")
+	require.ErrorIs(t, err, awsutils.ErrAccountNotAllowed)
+	_, err = factory.EC2("")
+	require.ErrorIs(t, err, awsutils.ErrAccountNotAllowed)
+	assert.Equal(t, 2, calls, "a refused account is checked again")
+}
+
+func TestMockClientFactory_BaseConfig(t *testing.T) {
+	t.Parallel()
+
+	factory := &awsutils.ClientFactory{
+		Target: awsutils.Target{Region: "us-east-1", RoleARN: "arn:aws:iam::111111111111:role/terratest"},
+		Loader: &MockAWSConfigLoader{
+			LoadConfigFunc: func(_ context.Context, region string) (aws.Config, error) {
+				return aws.Config{Region: region}, nil
+			},
+		},
+	}
+
+	// The profile is used as is, the role is not assumed.
+	cfg, err := factory.BaseConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", cfg.Region)
+	assert.Nil(t, cfg.Credentials)
+
+	assumed, err := factory.Config("")
+	require.NoError(t, err)
+	assert.NotNil(t, assumed.Credentials)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockDefaultAWSConfigLoader_MissingProfile(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(empty, nil, 0644))
+	t.Setenv("AWS_CONFIG_FILE", empty)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", empty)
+	t.Setenv("AWS_PROFILE", "")
+
+	// A missing profile must not fall back to the default credentials.
+	_, err := (&awsutils.DefaultAWSConfigLoader{Profile: "missing"}).LoadConfig(context.Background(), "us-east-1")
+	var notExist config.SharedConfigProfileNotExistError
+	require.ErrorAs(t, err, &notExist)
+	assert.ErrorContains(t, err, "profile missing")
+
+	cfg, err := (&awsutils.DefaultAWSConfigLoader{}).LoadConfig(context.Background(), "us-east-1")
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", cfg.Region)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockEnvAWSConfigLoader(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(file, []byte(`[profile terragrunt]
+aws_access_key_id = AKIAPROFILE
+aws_secret_access_key = secret
+`), 0644))
+	t.Setenv("AWS_CONFIG_FILE", file)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", file)
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAPROCESS")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	accessKey := func(env map[string]string) string {
+		cfg, err := (&awsutils.EnvAWSConfigLoader{Env: env}).LoadConfig(context.Background(), "us-east-1")
+		require.NoError(t, err)
+		creds, err := cfg.Credentials.Retrieve(context.Background())
+		require.NoError(t, err)
+
+		return creds.AccessKeyID
+	}
+
+	// Access keys of the process win over the profile of the options, as they do for terragrunt.
+	assert.Equal(t, "AKIAPROCESS", accessKey(map[string]string{"AWS_PROFILE": "terragrunt"}))
+	assert.Equal(t, "AKIAPROFILE", accessKey(map[string]string{"AWS_PROFILE": "terragrunt", "AWS_ACCESS_KEY_ID": ""}))
+	assert.Equal(t, "AKIAOPTIONS", accessKey(map[string]string{"AWS_ACCESS_KEY_ID": "AKIAOPTIONS", "AWS_SECRET_ACCESS_KEY": "secret"}))
+}
@@ -0,0 +1,120 @@
+package awsutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+// AccountGuard refuses to let the helpers run with credentials of an account outside the allow-list,
+// like allowed_account_ids in the example provider block.
+type AccountGuard struct {
+	Allowed []string
+	// Expected is the account_id of the vars file, empty if it has none.
+	Expected string
+	STS      STSClient
+
+	mu      sync.Mutex
+	checked bool
+}
+
+// NewAccountGuard returns the guard checking the credentials of awsCfg against cfg, build it from the
+// aws.Config of the clients it protects. It allows everything when cfg.AllowedAccountIDs is empty and fails
+// right away when the account of the vars file is not allowed.
+func NewAccountGuard(cfg core.RunTime, awsCfg aws.Config) (*AccountGuard, error) {
+	if len(cfg.AllowedAccountIDs) == 0 {
+		return &AccountGuard{}, nil
+	}
+
+	expected, err := expectedAccount(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountGuard{
+		Allowed:  cfg.AllowedAccountIDs,
+		Expected: expected,
+		STS:      sts.NewFromConfig(awsCfg),
+	}, nil
+}
+
+// Check asks STS which account the credentials belong to and fails unless it is allowed and expected.
+// A guard without allowed and expected accounts allows everything, a successful check is remembered.
+func (g *AccountGuard) Check(t *testing.T) error {
+	account, err := g.verify()
+	if err != nil {
+		return err
+	}
+	if account != "" {
+		logger.Log(t, "Account guard passed for account", account)
+	}
+
+	return nil
+}
+
+// checkGuard runs guard before a destructive call, a missing guard is refused instead of skipped.
+func checkGuard(t *testing.T, guard *AccountGuard) error {
+	if guard == nil {
+		return ErrNoAccountGuard
+	}
+
+	return guard.Check(t)
+}
+
+// verify does the check of Check, it returns the account when STS was asked.
+func (g *AccountGuard) verify() (string, error) {
+	if len(g.Allowed) == 0 && g.Expected == "" {
+		return "", nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.checked {
+		return "", nil
+	}
+
+	identity, err := g.STS.GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("account guard failed to get caller identity: %w", err)
+	}
+
+	account := aws.ToString(identity.Account)
+	if len(g.Allowed) > 0 && !slices.Contains(g.Allowed, account) {
+		return "", fmt.Errorf("%w: %s is not in %v", ErrAccountNotAllowed, aws.ToString(identity.Arn), g.Allowed)
+	}
+	if g.Expected != "" && account != g.Expected {
+		return "", fmt.Errorf("%w: credentials of %s are for account %s, expected %s",
+			ErrAccountMismatch, aws.ToString(identity.Arn), account, g.Expected)
+	}
+	g.checked = true
+
+	return account, nil
+}
+
+// expectedAccount returns the account_id of the vars file or cfg.AWS, it fails when the account is not allowed.
+func expectedAccount(cfg core.RunTime) (string, error) {
+	locals, err := core.ReadVarsLocals(cfg, core.OsFileSystem{})
+	if err != nil {
+		return "", err
+	}
+	expected := firstNonEmpty(cfg.AWS.AccountID, locals["account_id"])
+	if expected != "" && len(cfg.AllowedAccountIDs) > 0 && !slices.Contains(cfg.AllowedAccountIDs, expected) {
+		return "", fmt.Errorf("%w: %s of %s is not in %v", ErrAccountNotAllowed, expected, cfg.VarsFile, cfg.AllowedAccountIDs)
+	}
+
+	return expected, nil
+}
+
+var (
+	ErrAccountNotAllowed = errors.New("AWS account is not allowed")
+	ErrAccountMismatch   = errors.New("AWS credentials are for another account")
+	ErrNoAccountGuard    = errors.New("an account guard is required to delete AWS resources")
+)
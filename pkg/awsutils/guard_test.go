@@ -0,0 +1,139 @@
+package awsutils_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/awsutils"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/workmail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockAccountGuard(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	guard := &awsutils.AccountGuard{
+		Allowed:  []string{"111111111111", "222222222222"},
+		Expected: "111111111111",
+		STS:      callerIdentity("111111111111", &calls)(awsConfig()),
+	}
+
+	require.NoError(t, guard.Check(t))
+	require.NoError(t, guard.Check(t))
+	assert.Equal(t, 1, calls, "a passed check is remembered")
+}
+
+func TestMockAccountGuard_Fails(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	notAllowed := &awsutils.AccountGuard{
+		Allowed: []string{"111111111111"},
+		STS:     callerIdentity("999999999999", &calls)(awsConfig()),
+	}
+	require.ErrorIs(t, notAllowed.Check(t), awsutils.ErrAccountNotAllowed)
+	require.ErrorIs(t, notAllowed.Check(t), awsutils.ErrAccountNotAllowed)
+	assert.Equal(t, 2, calls, "a failed check is repeated")
+
+	unexpected := &awsutils.AccountGuard{
+		Allowed:  []string{"111111111111", "222222222222"},
+		Expected: "111111111111",
+		STS:      callerIdentity("222222222222", &calls)(awsConfig()),
+	}
+	require.ErrorIs(t, unexpected.Check(t), awsutils.ErrAccountMismatch)
+}
+
+func TestMockNewAccountGuard(t *testing.T) {
+	t.Parallel()
+
+	guard, err := awsutils.NewAccountGuard(core.RunTime{}, awsConfig())
+	require.NoError(t, err)
+	require.NoError(t, guard.Check(t), "without allowed accounts nothing is checked")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "root_vars.hcl"), []byte(`locals {
+  account_id = "111111111111"
+}
+`), 0644))
+
+	cfg := core.RunTime{
+		VarsFile:          "root_vars.hcl",
+		Paths:             core.FolderPaths{TerragruntDir: dir},
+		AllowedAccountIDs: []string{"222222222222"},
+	}
+	_, err = awsutils.NewAccountGuard(cfg, awsConfig())
+	require.ErrorIs(t, err, awsutils.ErrAccountNotAllowed)
+	assert.ErrorContains(t, err, "111111111111")
+
+	// The factory refuses the account before loading any credentials.
+	_, err = awsutils.NewClientFactoryFromConfig(cfg)
+	require.ErrorIs(t, err, awsutils.ErrAccountNotAllowed)
+
+	cfg.AllowedAccountIDs = []string{"111111111111"}
+	factory, err := awsutils.NewClientFactoryFromConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"111111111111"}, factory.Allowed)
+}
+
+func TestMockGuardedHelpers(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	refused := &awsutils.AccountGuard{
+		Allowed: []string{"111111111111"},
+		STS:     callerIdentity("999999999999", &calls)(awsConfig()),
+	}
+	// The clients fail the test if the helpers get past the guard.
+	workMail := &MockWorkMailClient{
+		DeleteOrganizationFunc: func(_ context.Context, _ *workmail.DeleteOrganizationInput, _ ...func(*workmail.Options)) (*workmail.DeleteOrganizationOutput, error) {
+			t.Error("organization deleted without a passed guard")
+
+			return &workmail.DeleteOrganizationOutput{}, nil
+		},
+	}
+	ec2Client := &MockEC2Client{
+		DescribeNetworkInterfacesFunc: func(_ context.Context, _ *ec2.DescribeNetworkInterfacesInput, _ ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+			t.Error("ENIs listed without a passed guard")
+
+			return &ec2.DescribeNetworkInterfacesOutput{}, nil
+		},
+	}
+	tagging := &MockTaggingClient{
+		GetResourcesFunc: func(_ context.Context, _ *resourcegroupstaggingapi.GetResourcesInput,
+			_ ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+			t.Error("resources listed without a passed guard")
+
+			return &resourcegroupstaggingapi.GetResourcesOutput{}, nil
+		},
+	}
+
+	for name, tc := range map[string]struct {
+		guard *awsutils.AccountGuard
+		want  error
+	}{
+		"missing": {guard: nil, want: awsutils.ErrNoAccountGuard},
+		"refused": {guard: refused, want: awsutils.ErrAccountNotAllowed},
+	} {
+		err := awsutils.DeleteWorkMailOrganization(t, tc.guard, "test-org-id", workMail)
+		require.ErrorIs(t, err, tc.want, name)
+
+		_, err = awsutils.RemoveENI(t, tc.guard, "vpc-123456", ec2Client)
+		require.ErrorIs(t, err, tc.want, name)
+
+		_, err = awsutils.ReleaseENIs(t, tc.guard, ec2Client, &MockSleeper{}, awsutils.ENIReleaseOptions{
+			ENICleanupOptions: awsutils.ENICleanupOptions{VPCID: "vpc-123456", Tags: map[string]string{"tt_run_id": "run-1"}},
+		})
+		require.ErrorIs(t, err, tc.want, name)
+
+		_, err = awsutils.SweepTaggedResources(t, awsutils.SweepClients{Guard: tc.guard, Tagging: tagging},
+			awsutils.SweepOptions{RunTagKey: "tt_run_id", RunTagValue: "run-1"})
+		require.ErrorIs(t, err, tc.want, name)
+	}
+}
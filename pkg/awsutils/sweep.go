@@ -0,0 +1,427 @@
+package awsutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	taggingtypes "github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// iamRegions maps the region prefix of a partition to the region where the Tagging API lists its global IAM
+// resources, regions of the aws partition use us-east-1.
+var iamRegions = map[string]string{
+	"us-gov-":  "us-gov-west-1",
+	"cn-":      "cn-north-1",
+	"us-isob-": "us-isob-east-1",
+	"us-iso-":  "us-iso-east-1",
+}
+
+// IAMRegion returns the region where the Tagging API lists the IAM resources of the partition region belongs to.
+func IAMRegion(region string) string {
+	for prefix, iam := range iamRegions {
+		if strings.HasPrefix(region, prefix) {
+			return iam
+		}
+	}
+
+	return "us-east-1"
+}
+
+// sweptResourceTypes are the resource types SweepTaggedResources can delete.
+var sweptResourceTypes = []string{
+	"ec2:network-interface",
+	"ec2:security-group",
+	"logs:log-group",
+	"iam:policy",
+}
+
+type TaggingClient interface {
+	GetResources(ctx context.Context, params *resourcegroupstaggingapi.GetResourcesInput, optFns ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error)
+}
+
+type SecurityGroupClient interface {
+	DeleteSecurityGroup(ctx context.Context, params *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error)
+}
+
+type IAMClient interface {
+	ListEntitiesForPolicy(ctx context.Context, params *iam.ListEntitiesForPolicyInput, optFns ...func(*iam.Options)) (*iam.ListEntitiesForPolicyOutput, error)
+	DetachRolePolicy(ctx context.Context, params *iam.DetachRolePolicyInput, optFns ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error)
+	DetachUserPolicy(ctx context.Context, params *iam.DetachUserPolicyInput, optFns ...func(*iam.Options)) (*iam.DetachUserPolicyOutput, error)
+	DetachGroupPolicy(ctx context.Context, params *iam.DetachGroupPolicyInput, optFns ...func(*iam.Options)) (*iam.DetachGroupPolicyOutput, error)
+	ListPolicyVersions(ctx context.Context, params *iam.ListPolicyVersionsInput, optFns ...func(*iam.Options)) (*iam.ListPolicyVersionsOutput, error)
+	DeletePolicyVersion(ctx context.Context, params *iam.DeletePolicyVersionInput, optFns ...func(*iam.Options)) (*iam.DeletePolicyVersionOutput, error)
+	DeletePolicy(ctx context.Context, params *iam.DeletePolicyInput, optFns ...func(*iam.Options)) (*iam.DeletePolicyOutput, error)
+}
+
+type LogsClient interface {
+	DeleteLogGroup(ctx context.Context, params *cloudwatchlogs.DeleteLogGroupInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DeleteLogGroupOutput, error)
+}
+
+// SweepClients are the AWS clients used by SweepTaggedResources.
+type SweepClients struct {
+	// Guard checks the account of the clients before anything is deleted, ClientFactory.Sweep sets it.
+	Guard   *AccountGuard
+	Tagging TaggingClient
+	// IAMTagging finds the IAM policies, it is a Tagging client of the IAM region of the partition, like
+	// us-east-1 or us-gov-west-1. Policies are not swept when it is nil.
+	IAMTagging     TaggingClient
+	EC2            EC2Client
+	SecurityGroups SecurityGroupClient
+	IAM            IAMClient
+	Logs           LogsClient
+}
+
+// LoadSweepClients creates the sweeper clients for region once STS confirmed the account of cfg, see
+// ClientFactory.Sweep.
+func LoadSweepClients(cfg core.RunTime, region string) (SweepClients, error) {
+	factory, err := NewClientFactoryFromConfig(cfg)
+	if err != nil {
+		return SweepClients{}, err
+	}
+
+	return factory.Sweep(region)
+}
+
+func newSweepClients(cfg aws.Config) SweepClients {
+	ec2Client := ec2.NewFromConfig(cfg)
+	iamCfg := cfg.Copy()
+	iamCfg.Region = IAMRegion(cfg.Region)
+
+	return SweepClients{
+		Tagging:        resourcegroupstaggingapi.NewFromConfig(cfg),
+		IAMTagging:     resourcegroupstaggingapi.NewFromConfig(iamCfg),
+		EC2:            ec2Client,
+		SecurityGroups: ec2Client,
+		IAM:            iam.NewFromConfig(cfg),
+		Logs:           cloudwatchlogs.NewFromConfig(cfg),
+	}
+}
+
+// SweepOptions selects the resources to sweep, a resource must carry every tag.
+type SweepOptions struct {
+	// Tags are usually the mandatory tags, see ReadMandatoryTags.
+	Tags map[string]string
+	// RunTagKey and RunTagValue identify a single test run, they are required so a sweep
+	// never touches resources of other runs that share the mandatory tags.
+	RunTagKey   string
+	RunTagValue string
+}
+
+func (o SweepOptions) filterTags() map[string]string {
+	tags := make(map[string]string, len(o.Tags)+1)
+	for key, value := range o.Tags {
+		tags[key] = value
+	}
+	tags[o.RunTagKey] = o.RunTagValue
+
+	return tags
+}
+
+// SweepReport lists what the sweep deleted and what it could not.
+type SweepReport struct {
+	Deleted     []string
+	Failed      map[string]error
+	Unsupported []string
+}
+
+// Err joins the deletion failures sorted by ARN, nil if everything found was deleted.
+func (r SweepReport) Err() error {
+	arns := make([]string, 0, len(r.Failed))
+	for arn := range r.Failed {
+		arns = append(arns, arn)
+	}
+	sort.Strings(arns)
+
+	errs := make([]error, 0, len(arns))
+	for _, arn := range arns {
+		errs = append(errs, fmt.Errorf("%s: %w", arn, r.Failed[arn]))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *SweepReport) record(arn string, err error) {
+	if err != nil {
+		r.Failed[arn] = err
+
+		return
+	}
+	r.Deleted = append(r.Deleted, arn)
+}
+
+// ReadMandatoryTags reads locals.mandatory_tags from an HCL file such as example/mandatory_tags.hcl.
+// Values are converted to strings the way terraform tags them, dept_code = 000 becomes "0".
+func ReadMandatoryTags(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("readFile func failed to read %s: %w", path, err)
+	}
+
+	file, diags := hclsyntax.ParseConfig(content, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, diags)
+	}
+
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "locals" {
+			continue
+		}
+		attr, ok := block.Body.Attributes["mandatory_tags"]
+		if !ok {
+			continue
+		}
+
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to evaluate mandatory_tags in %s: %w", path, diags)
+		}
+		if !value.Type().IsObjectType() && !value.Type().IsMapType() {
+			return nil, fmt.Errorf("mandatory_tags in %s is not a map", path)
+		}
+
+		tags := map[string]string{}
+		for key, element := range value.AsValueMap() {
+			str, err := convert.Convert(element, cty.String)
+			if err != nil {
+				return nil, fmt.Errorf("mandatory tag %s in %s: %w", key, path, err)
+			}
+			tags[key] = str.AsString()
+		}
+
+		return tags, nil
+	}
+
+	return nil, fmt.Errorf("%w in %s", ErrNoMandatoryTags, path)
+}
+
+// SweepTaggedResources deletes the ENIs, security groups, log groups and IAM policies still carrying every
+// tag of opts after a destroy. Anything that cannot be deleted is reported, not returned as an error early.
+// Nothing is listed unless clients.Guard passed.
+func SweepTaggedResources(t *testing.T, clients SweepClients, opts SweepOptions) (SweepReport, error) {
+	report := SweepReport{Failed: map[string]error{}}
+	if opts.RunTagKey == "" || opts.RunTagValue == "" {
+		return report, ErrNoRunTag
+	}
+	if err := checkGuard(t, clients.Guard); err != nil {
+		return report, err
+	}
+
+	logger.Log(t, "Sweep resources tagged", opts.RunTagKey+"="+opts.RunTagValue)
+
+	byType, err := taggedByType(clients, opts.filterTags())
+	if err != nil {
+		return report, err
+	}
+
+	// ENIs hold on to security groups, delete them first.
+	for _, arn := range byType["ec2:network-interface"] {
+		report.record(arn, deleteENI(clients.EC2, arnResourceID(arn)))
+	}
+	for _, arn := range byType["ec2:security-group"] {
+		report.record(arn, deleteSecurityGroup(clients.SecurityGroups, arnResourceID(arn)))
+	}
+	for _, arn := range byType["logs:log-group"] {
+		report.record(arn, deleteLogGroup(clients.Logs, logGroupName(arn)))
+	}
+	for _, arn := range byType["iam:policy"] {
+		report.record(arn, deletePolicy(clients.IAM, arn))
+	}
+	for resourceType, found := range byType {
+		if !isSweptType(resourceType) {
+			report.Unsupported = append(report.Unsupported, found...)
+		}
+	}
+	sort.Strings(report.Unsupported)
+
+	logger.Log(t, "Sweep deleted", len(report.Deleted), "resources, failed", len(report.Failed), "unsupported", len(report.Unsupported))
+	for _, arn := range report.Unsupported {
+		logger.Log(t, "Sweep cannot delete", arn)
+	}
+
+	return report, report.Err()
+}
+
+// taggedByType returns the ARNs carrying every tag grouped by resource type, such as ec2:security-group.
+func taggedByType(clients SweepClients, tags map[string]string) (map[string][]string, error) {
+	arns, err := findTaggedResources(clients.Tagging, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	// IAM is global, the Tagging API lists its policies in the IAM region only.
+	if clients.IAMTagging != nil {
+		policies, err := findTaggedResources(clients.IAMTagging, tags, "iam:policy")
+		if err != nil {
+			return nil, err
+		}
+		arns = append(arns, policies...)
+	}
+
+	byType := map[string][]string{}
+	seen := map[string]bool{}
+	for _, arn := range arns {
+		if seen[arn] {
+			continue
+		}
+		seen[arn] = true
+		resourceType := arnResourceType(arn)
+		byType[resourceType] = append(byType[resourceType], arn)
+	}
+
+	return byType, nil
+}
+
+// findTaggedResources returns the ARNs carrying every tag, of resourceTypes only when given.
+func findTaggedResources(client TaggingClient, tags map[string]string, resourceTypes ...string) ([]string, error) {
+	filters := make([]taggingtypes.TagFilter, 0, len(tags))
+	for key, value := range tags {
+		filters = append(filters, taggingtypes.TagFilter{Key: aws.String(key), Values: []string{value}})
+	}
+	sort.Slice(filters, func(i, j int) bool { return *filters[i].Key < *filters[j].Key })
+
+	var arns []string
+	input := &resourcegroupstaggingapi.GetResourcesInput{TagFilters: filters, ResourceTypeFilters: resourceTypes}
+	for {
+		output, err := client.GetResources(context.TODO(), input)
+		if err != nil {
+			return nil, fmt.Errorf("error getting tagged resources: %w", err)
+		}
+		for _, mapping := range output.ResourceTagMappingList {
+			arns = append(arns, aws.ToString(mapping.ResourceARN))
+		}
+
+		if aws.ToString(output.PaginationToken) == "" {
+			return arns, nil
+		}
+		input.PaginationToken = output.PaginationToken
+	}
+}
+
+func deleteENI(client EC2Client, id string) error {
+	_, err := client.DeleteNetworkInterface(context.TODO(), &ec2.DeleteNetworkInterfaceInput{NetworkInterfaceId: aws.String(id)})
+
+	return err
+}
+
+func deleteSecurityGroup(client SecurityGroupClient, id string) error {
+	_, err := client.DeleteSecurityGroup(context.TODO(), &ec2.DeleteSecurityGroupInput{GroupId: aws.String(id)})
+
+	return err
+}
+
+func deleteLogGroup(client LogsClient, name string) error {
+	_, err := client.DeleteLogGroup(context.TODO(), &cloudwatchlogs.DeleteLogGroupInput{LogGroupName: aws.String(name)})
+
+	return err
+}
+
+// deletePolicy detaches the policy and removes its non default versions, IAM refuses to delete it otherwise.
+func deletePolicy(client IAMClient, arn string) error {
+	paginator := iam.NewListEntitiesForPolicyPaginator(client, &iam.ListEntitiesForPolicyInput{PolicyArn: aws.String(arn)})
+	for paginator.HasMorePages() {
+		entities, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return fmt.Errorf("error listing entities: %w", err)
+		}
+		if err := detachPolicy(client, arn, entities); err != nil {
+			return err
+		}
+	}
+
+	versions, err := client.ListPolicyVersions(context.TODO(), &iam.ListPolicyVersionsInput{PolicyArn: aws.String(arn)})
+	if err != nil {
+		return fmt.Errorf("error listing versions: %w", err)
+	}
+	for _, version := range versions.Versions {
+		if version.IsDefaultVersion {
+			continue
+		}
+		if _, err := client.DeletePolicyVersion(context.TODO(), &iam.DeletePolicyVersionInput{PolicyArn: aws.String(arn), VersionId: version.VersionId}); err != nil {
+			return fmt.Errorf("error deleting version %s: %w", aws.ToString(version.VersionId), err)
+		}
+	}
+
+	_, err = client.DeletePolicy(context.TODO(), &iam.DeletePolicyInput{PolicyArn: aws.String(arn)})
+
+	return err
+}
+
+// detachPolicy detaches the policy from the roles, users and groups of a page of entities.
+func detachPolicy(client IAMClient, arn string, entities *iam.ListEntitiesForPolicyOutput) error {
+	for _, role := range entities.PolicyRoles {
+		if _, err := client.DetachRolePolicy(context.TODO(), &iam.DetachRolePolicyInput{PolicyArn: aws.String(arn), RoleName: role.RoleName}); err != nil {
+			return fmt.Errorf("error detaching from role %s: %w", aws.ToString(role.RoleName), err)
+		}
+	}
+	for _, user := range entities.PolicyUsers {
+		if _, err := client.DetachUserPolicy(context.TODO(), &iam.DetachUserPolicyInput{PolicyArn: aws.String(arn), UserName: user.UserName}); err != nil {
+			return fmt.Errorf("error detaching from user %s: %w", aws.ToString(user.UserName), err)
+		}
+	}
+	for _, group := range entities.PolicyGroups {
+		if _, err := client.DetachGroupPolicy(context.TODO(), &iam.DetachGroupPolicyInput{PolicyArn: aws.String(arn), GroupName: group.GroupName}); err != nil {
+			return fmt.Errorf("error detaching from group %s: %w", aws.ToString(group.GroupName), err)
+		}
+	}
+
+	return nil
+}
+
+// arnResourceType returns service:type, for example ec2:network-interface for
+// arn:aws:ec2:us-east-1:123456789012:network-interface/eni-0123.
+func arnResourceType(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return arn
+	}
+
+	resource := parts[5]
+	if i := strings.IndexAny(resource, "/:"); i >= 0 {
+		resource = resource[:i]
+	}
+
+	return parts[2] + ":" + resource
+}
+
+// arnResourceID returns the part after the last slash.
+func arnResourceID(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+// logGroupName handles both arn:...:log-group:name and arn:...:log-group:name:*.
+func logGroupName(arn string) string {
+	name := arn[strings.Index(arn, ":log-group:")+len(":log-group:"):]
+
+	return strings.TrimSuffix(name, ":*")
+}
+
+func isSweptType(resourceType string) bool {
+	for _, swept := range sweptResourceTypes {
+		if swept == resourceType {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	ErrNoRunTag        = errors.New("a run tag is required to sweep resources")
+	ErrNoMandatoryTags = errors.New("no locals.mandatory_tags found")
+)
@@ -0,0 +1,248 @@
+package awsutils_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/pkg/awsutils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	taggingtypes "github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type getResourcesFunc = func(ctx context.Context, params *resourcegroupstaggingapi.GetResourcesInput,
+	optFns ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error)
+
+type MockTaggingClient struct {
+	GetResourcesFunc getResourcesFunc
+}
+
+func (m *MockTaggingClient) GetResources(ctx context.Context, params *resourcegroupstaggingapi.GetResourcesInput,
+	optFns ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+	return m.GetResourcesFunc(ctx, params, optFns...)
+}
+
+type MockSecurityGroupClient struct {
+	DeleteSecurityGroupFunc func(ctx context.Context, params *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error)
+}
+
+func (m *MockSecurityGroupClient) DeleteSecurityGroup(ctx context.Context, params *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
+	return m.DeleteSecurityGroupFunc(ctx, params, optFns...)
+}
+
+type MockLogsClient struct {
+	DeleteLogGroupFunc func(ctx context.Context, params *cloudwatchlogs.DeleteLogGroupInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DeleteLogGroupOutput, error)
+}
+
+func (m *MockLogsClient) DeleteLogGroup(ctx context.Context, params *cloudwatchlogs.DeleteLogGroupInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DeleteLogGroupOutput, error) {
+	return m.DeleteLogGroupFunc(ctx, params, optFns...)
+}
+
+// MockIAMClient records the calls.
+type MockIAMClient struct {
+	versions []iamtypes.PolicyVersion
+	roles    []iamtypes.PolicyRole
+	calls    []string
+}
+
+// ListEntitiesForPolicy returns a role per page.
+func (m *MockIAMClient) ListEntitiesForPolicy(_ context.Context, params *iam.ListEntitiesForPolicyInput, _ ...func(*iam.Options)) (*iam.ListEntitiesForPolicyOutput, error) {
+	page := 0
+	if params.Marker != nil {
+		page, _ = strconv.Atoi(*params.Marker)
+	}
+	if page >= len(m.roles) {
+		return &iam.ListEntitiesForPolicyOutput{}, nil
+	}
+
+	output := &iam.ListEntitiesForPolicyOutput{PolicyRoles: m.roles[page : page+1]}
+	if page+1 < len(m.roles) {
+		output.IsTruncated = true
+		output.Marker = aws.String(strconv.Itoa(page + 1))
+	}
+
+	return output, nil
+}
+
+func (m *MockIAMClient) DetachRolePolicy(_ context.Context, params *iam.DetachRolePolicyInput, _ ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error) {
+	m.calls = append(m.calls, "detach role "+aws.ToString(params.RoleName))
+
+	return &iam.DetachRolePolicyOutput{}, nil
+}
+
+func (m *MockIAMClient) DetachUserPolicy(_ context.Context, params *iam.DetachUserPolicyInput, _ ...func(*iam.Options)) (*iam.DetachUserPolicyOutput, error) {
+	m.calls = append(m.calls, "detach user "+aws.ToString(params.UserName))
+
+	return &iam.DetachUserPolicyOutput{}, nil
+}
+
+func (m *MockIAMClient) DetachGroupPolicy(_ context.Context, params *iam.DetachGroupPolicyInput, _ ...func(*iam.Options)) (*iam.DetachGroupPolicyOutput, error) {
+	m.calls = append(m.calls, "detach group "+aws.ToString(params.GroupName))
+
+	return &iam.DetachGroupPolicyOutput{}, nil
+}
+
+func (m *MockIAMClient) ListPolicyVersions(_ context.Context, _ *iam.ListPolicyVersionsInput, _ ...func(*iam.Options)) (*iam.ListPolicyVersionsOutput, error) {
+	return &iam.ListPolicyVersionsOutput{Versions: m.versions}, nil
+}
+
+func (m *MockIAMClient) DeletePolicyVersion(_ context.Context, params *iam.DeletePolicyVersionInput, _ ...func(*iam.Options)) (*iam.DeletePolicyVersionOutput, error) {
+	m.calls = append(m.calls, "delete version "+aws.ToString(params.VersionId))
+
+	return &iam.DeletePolicyVersionOutput{}, nil
+}
+
+func (m *MockIAMClient) DeletePolicy(_ context.Context, params *iam.DeletePolicyInput, _ ...func(*iam.Options)) (*iam.DeletePolicyOutput, error) {
+	m.calls = append(m.calls, "delete policy "+aws.ToString(params.PolicyArn))
+
+	return &iam.DeletePolicyOutput{}, nil
+}
+
+func TestMockReadMandatoryTags(t *testing.T) {
+	t.Parallel()
+
+	tags, err := awsutils.ReadMandatoryTags(filepath.Join("..", "..", "example", "mandatory_tags.hcl"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "0", tags["dept_code"])
+	assert.Equal(t, "terraform", tags["managed_by"])
+	assert.Len(t, tags, 7)
+}
+
+func TestMockSweepTaggedResources(t *testing.T) {
+	t.Parallel()
+
+	const (
+		eni       = "arn:aws:ec2:us-east-1:123456789012:network-interface/eni-0123"
+		sg        = "arn:aws:ec2:us-east-1:123456789012:security-group/sg-0123"
+		logGroup  = "arn:aws:logs:us-east-1:123456789012:log-group:/aws/lambda/test:*"
+		policy    = "arn:aws:iam::123456789012:policy/test"
+		bucket    = "arn:aws:s3:::test-bucket"
+		runTagKey = "tt_run_id"
+	)
+
+	var order []string
+	var filters []taggingtypes.TagFilter
+	var policyTypes []string
+	pages := 0
+	clients := awsutils.SweepClients{
+		Guard: passingGuard(),
+		Tagging: &MockTaggingClient{
+			GetResourcesFunc: func(_ context.Context, params *resourcegroupstaggingapi.GetResourcesInput,
+				_ ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+				filters = params.TagFilters
+				pages++
+				if params.PaginationToken == nil {
+					return &resourcegroupstaggingapi.GetResourcesOutput{
+						ResourceTagMappingList: []taggingtypes.ResourceTagMapping{{ResourceARN: aws.String(sg)}},
+						PaginationToken:        aws.String("next"),
+					}, nil
+				}
+
+				return &resourcegroupstaggingapi.GetResourcesOutput{
+					ResourceTagMappingList: []taggingtypes.ResourceTagMapping{{ResourceARN: aws.String(eni)}, {ResourceARN: aws.String(logGroup)}, {ResourceARN: aws.String(bucket)}},
+				}, nil
+			},
+		},
+		IAMTagging: &MockTaggingClient{
+			GetResourcesFunc: func(_ context.Context, params *resourcegroupstaggingapi.GetResourcesInput,
+				_ ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+				policyTypes = params.ResourceTypeFilters
+
+				return &resourcegroupstaggingapi.GetResourcesOutput{
+					ResourceTagMappingList: []taggingtypes.ResourceTagMapping{{ResourceARN: aws.String(policy)}},
+				}, nil
+			},
+		},
+		EC2: &MockEC2Client{
+			DeleteNetworkInterfaceFunc: func(_ context.Context, params *ec2.DeleteNetworkInterfaceInput, _ ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error) {
+				order = append(order, aws.ToString(params.NetworkInterfaceId))
+
+				return &ec2.DeleteNetworkInterfaceOutput{}, nil
+			},
+		},
+		SecurityGroups: &MockSecurityGroupClient{
+			DeleteSecurityGroupFunc: func(_ context.Context, params *ec2.DeleteSecurityGroupInput, _ ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
+				order = append(order, aws.ToString(params.GroupId))
+
+				return &ec2.DeleteSecurityGroupOutput{}, nil
+			},
+		},
+		Logs: &MockLogsClient{
+			DeleteLogGroupFunc: func(_ context.Context, params *cloudwatchlogs.DeleteLogGroupInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DeleteLogGroupOutput, error) {
+				order = append(order, aws.ToString(params.LogGroupName))
+
+				return &cloudwatchlogs.DeleteLogGroupOutput{}, nil
+			},
+		},
+	}
+	iamClient := &MockIAMClient{
+		versions: []iamtypes.PolicyVersion{{VersionId: aws.String("v1")}, {VersionId: aws.String("v2"), IsDefaultVersion: true}},
+		roles:    []iamtypes.PolicyRole{{RoleName: aws.String("lambda")}, {RoleName: aws.String("ecs")}},
+	}
+	clients.IAM = iamClient
+
+	report, err := awsutils.SweepTaggedResources(t, clients, awsutils.SweepOptions{
+		Tags:        map[string]string{"managed_by": "terraform"},
+		RunTagKey:   runTagKey,
+		RunTagValue: "run-1",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, pages)
+	assert.Len(t, filters, 2)
+	assert.Equal(t, []string{"iam:policy"}, policyTypes)
+	assert.Equal(t, []string{"eni-0123", "sg-0123", "/aws/lambda/test"}, order)
+	assert.Equal(t, []string{"detach role lambda", "detach role ecs", "delete version v1", "delete policy " + policy}, iamClient.calls)
+	assert.ElementsMatch(t, []string{eni, sg, logGroup, policy}, report.Deleted)
+	assert.Equal(t, []string{bucket}, report.Unsupported)
+	assert.Empty(t, report.Failed)
+}
+
+func TestMockSweepTaggedResourcesFailures(t *testing.T) {
+	t.Parallel()
+
+	const sg = "arn:aws:ec2:us-east-1:123456789012:security-group/sg-0123"
+
+	clients := awsutils.SweepClients{
+		Guard: passingGuard(),
+		Tagging: &MockTaggingClient{
+			GetResourcesFunc: func(_ context.Context, _ *resourcegroupstaggingapi.GetResourcesInput, _ ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+				return &resourcegroupstaggingapi.GetResourcesOutput{
+					ResourceTagMappingList: []taggingtypes.ResourceTagMapping{{ResourceARN: aws.String(sg)}},
+				}, nil
+			},
+		},
+		SecurityGroups: &MockSecurityGroupClient{
+			DeleteSecurityGroupFunc: func(_ context.Context, _ *ec2.DeleteSecurityGroupInput, _ ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
+				return nil, errors.New("DependencyViolation")
+			},
+		},
+	}
+
+	report, err := awsutils.SweepTaggedResources(t, clients, awsutils.SweepOptions{RunTagKey: "tt_run_id", RunTagValue: "run-1"})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, sg)
+	assert.Contains(t, report.Failed, sg)
+	assert.Empty(t, report.Deleted)
+
+	_, err = awsutils.SweepTaggedResources(t, clients, awsutils.SweepOptions{Tags: map[string]string{"managed_by": "terraform"}})
+	assert.ErrorIs(t, err, awsutils.ErrNoRunTag)
+}
+
+func TestMockIAMRegion(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "us-east-1", awsutils.IAMRegion("eu-west-1"))
+	assert.Equal(t, "us-gov-west-1", awsutils.IAMRegion("us-gov-east-1"))
+	assert.Equal(t, "cn-north-1", awsutils.IAMRegion("cn-northwest-1"))
+}
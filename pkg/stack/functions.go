@@ -0,0 +1,191 @@
+package stack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// evalContext supports the terragrunt path and environment functions used to build include and dependency paths.
+// It is evaluated from the point of view of the module in dir, also for included files. Functions that need
+// terragrunt itself, like run_cmd or read_terragrunt_config, are not supported.
+func evalContext(dir string, locals map[string]cty.Value) *hcl.EvalContext {
+	return &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"local": cty.ObjectVal(locals),
+		},
+		Functions: map[string]function.Function{
+			"find_in_parent_folders":         findInParentFoldersFunc(dir),
+			"get_terragrunt_dir":             constantFunc(dir),
+			"get_parent_terragrunt_dir":      parentTerragruntDirFunc(dir),
+			"get_original_terragrunt_dir":    constantFunc(dir),
+			"get_working_dir":                constantFunc(dir),
+			"get_repo_root":                  repoRootFunc(dir, func(root string) (string, error) { return root, nil }),
+			"get_path_to_repo_root":          repoRootFunc(dir, func(root string) (string, error) { return relPath(dir, root) }),
+			"get_path_from_repo_root":        repoRootFunc(dir, func(root string) (string, error) { return relPath(root, dir) }),
+			"path_relative_to_include":       constantFunc("."),
+			"path_relative_from_include":     constantFunc("."),
+			"get_terragrunt_source_cli_flag": constantFunc(os.Getenv("TERRAGRUNT_SOURCE")),
+			"get_platform":                   constantFunc(runtime.GOOS),
+			"get_env":                        getEnvFunc,
+			"dirname":                        pathFunc(filepath.Dir),
+			"basename":                       pathFunc(filepath.Base),
+			"abspath":                        pathFunc(func(path string) string { return absPath(dir, path) }),
+			"format":                         stdlib.FormatFunc,
+			"join":                           stdlib.JoinFunc,
+			"split":                          stdlib.SplitFunc,
+			"element":                        stdlib.ElementFunc,
+			"lower":                          stdlib.LowerFunc,
+			"upper":                          stdlib.UpperFunc,
+			"replace":                        stdlib.ReplaceFunc,
+			"trimprefix":                     stdlib.TrimPrefixFunc,
+			"trimsuffix":                     stdlib.TrimSuffixFunc,
+			"trimspace":                      stdlib.TrimSpaceFunc,
+			"coalesce":                       stdlib.CoalesceFunc,
+			"concat":                         stdlib.ConcatFunc,
+		},
+	}
+}
+
+// evalLocals resolves the locals that only depend on supported functions and other locals.
+// Locals that cannot be resolved, such as read_terragrunt_config calls, are left out.
+func evalLocals(dir string, attrs hclsyntax.Attributes) map[string]cty.Value {
+	locals := map[string]cty.Value{}
+	pending := map[string]*hclsyntax.Attribute{}
+	for name, attr := range attrs {
+		pending[name] = attr
+	}
+
+	// Each pass resolves at least one local or nothing more can be resolved.
+	for progress := true; progress && len(pending) > 0; {
+		progress = false
+		for name, attr := range pending {
+			value, diags := attr.Expr.Value(evalContext(dir, locals))
+			if diags.HasErrors() || !value.IsWhollyKnown() {
+				continue
+			}
+			locals[name] = value
+			delete(pending, name)
+			progress = true
+		}
+	}
+
+	return locals
+}
+
+func constantFunc(value string) function.Function {
+	return function.New(&function.Spec{
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(_ []cty.Value, _ cty.Type) (cty.Value, error) {
+			return cty.StringVal(value), nil
+		},
+	})
+}
+
+func pathFunc(fn func(string) string) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{{Name: "path", Type: cty.String}},
+		Type:   function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			return cty.StringVal(fn(args[0].AsString())), nil
+		},
+	})
+}
+
+// findInParentFoldersFunc mirrors terragrunt, the search starts in the parent of dir.
+func findInParentFoldersFunc(dir string) function.Function {
+	return function.New(&function.Spec{
+		VarParam: &function.Parameter{Name: "args", Type: cty.String},
+		Type:     function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			name := "terragrunt.hcl"
+			if len(args) > 0 {
+				name = args[0].AsString()
+			}
+
+			if found, ok := core.FindInParents(filepath.Dir(dir), name); ok {
+				return cty.StringVal(found), nil
+			}
+			if len(args) > 1 {
+				return args[1], nil
+			}
+
+			return cty.NilVal, fmt.Errorf("%s not found in parent folders of %s", name, dir)
+		},
+	})
+}
+
+func parentTerragruntDirFunc(dir string) function.Function {
+	return function.New(&function.Spec{
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(_ []cty.Value, _ cty.Type) (cty.Value, error) {
+			if found, ok := core.FindInParents(filepath.Dir(dir), "terragrunt.hcl"); ok {
+				return cty.StringVal(filepath.Dir(found)), nil
+			}
+
+			return cty.StringVal(dir), nil
+		},
+	})
+}
+
+// getEnvFunc mirrors terragrunt's get_env(name, default), an unset variable without default is an error.
+var getEnvFunc = function.New(&function.Spec{
+	Params:   []function.Parameter{{Name: "name", Type: cty.String}},
+	VarParam: &function.Parameter{Name: "default", Type: cty.String},
+	Type:     function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+		name := args[0].AsString()
+		if value, ok := os.LookupEnv(name); ok {
+			return cty.StringVal(value), nil
+		}
+		if len(args) > 1 {
+			return args[1], nil
+		}
+
+		return cty.NilVal, fmt.Errorf("environment variable %s is not set", name)
+	},
+})
+
+// repoRootFunc passes the root of the git repository holding dir to fn.
+func repoRootFunc(dir string, fn func(root string) (string, error)) function.Function {
+	return function.New(&function.Spec{
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(_ []cty.Value, _ cty.Type) (cty.Value, error) {
+			found, ok := core.FindInParents(dir, ".git")
+			if !ok {
+				return cty.NilVal, fmt.Errorf("%s is not inside a git repository", dir)
+			}
+			value, err := fn(filepath.Dir(found))
+			if err != nil {
+				return cty.NilVal, err
+			}
+
+			return cty.StringVal(value), nil
+		},
+	})
+}
+
+func relPath(base, target string) (string, error) {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.ToSlash(rel), nil
+}
+
+func absPath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+
+	return filepath.Join(dir, path)
+}
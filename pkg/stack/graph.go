@@ -0,0 +1,252 @@
+package stack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Paths returns the module paths sorted.
+func (g *Graph) Paths() []string {
+	paths := make([]string, 0, len(g.Modules))
+	for path := range g.Modules {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// Dependents returns the modules that depend directly on path.
+func (g *Graph) Dependents(path string) []string {
+	var dependents []string
+	for _, candidate := range g.Paths() {
+		for _, dependency := range g.Modules[candidate].Dependencies {
+			if dependency == path {
+				dependents = append(dependents, candidate)
+
+				break
+			}
+		}
+	}
+
+	return dependents
+}
+
+// Roots returns the modules without dependencies, they are applied first.
+func (g *Graph) Roots() []string {
+	var roots []string
+	for _, path := range g.Paths() {
+		if len(g.Modules[path].Dependencies) == 0 {
+			roots = append(roots, path)
+		}
+	}
+
+	return roots
+}
+
+// Leaves returns the modules nothing depends on, they are destroyed first.
+func (g *Graph) Leaves() []string {
+	depended := map[string]bool{}
+	for _, module := range g.Modules {
+		for _, dependency := range module.Dependencies {
+			depended[dependency] = true
+		}
+	}
+
+	var leaves []string
+	for _, path := range g.Paths() {
+		if !depended[path] {
+			leaves = append(leaves, path)
+		}
+	}
+
+	return leaves
+}
+
+// Cycles returns every group of modules that depend on each other, each sorted.
+func (g *Graph) Cycles() [][]string {
+	// Tarjan's strongly connected components.
+	index := 0
+	indexes := map[string]int{}
+	lowlinks := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var cycles [][]string
+
+	var connect func(path string)
+	connect = func(path string) {
+		indexes[path] = index
+		lowlinks[path] = index
+		index++
+		stack = append(stack, path)
+		onStack[path] = true
+
+		selfLoop := false
+		for _, dependency := range g.Modules[path].Dependencies {
+			if dependency == path {
+				selfLoop = true
+			}
+			if _, visited := indexes[dependency]; !visited {
+				connect(dependency)
+				lowlinks[path] = min(lowlinks[path], lowlinks[dependency])
+			} else if onStack[dependency] {
+				lowlinks[path] = min(lowlinks[path], indexes[dependency])
+			}
+		}
+
+		if lowlinks[path] != indexes[path] {
+			return
+		}
+
+		var component []string
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component = append(component, last)
+			if last == path {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, path := range g.Paths() {
+		if _, visited := indexes[path]; !visited {
+			connect(path)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+
+	return cycles
+}
+
+// TopologicalOrder returns the modules with every module after its dependencies.
+// Modules at the same depth are sorted by path so the order is stable.
+func (g *Graph) TopologicalOrder() ([]string, error) {
+	if cycles := g.Cycles(); len(cycles) > 0 {
+		var groups []string
+		for _, cycle := range cycles {
+			groups = append(groups, strings.Join(cycle, " -> "))
+		}
+
+		return nil, fmt.Errorf("%w: %s", ErrCycle, strings.Join(groups, "; "))
+	}
+
+	remaining := map[string]int{}
+	for path, module := range g.Modules {
+		remaining[path] = len(module.Dependencies)
+	}
+
+	var order []string
+	ready := g.Roots()
+	for len(ready) > 0 {
+		order = append(order, ready...)
+
+		var next []string
+		for _, path := range ready {
+			for _, dependent := range g.Dependents(path) {
+				remaining[dependent]--
+				if remaining[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		sort.Strings(next)
+		ready = next
+	}
+
+	return order, nil
+}
+
+// WithDependencies returns the subgraph of paths and everything they depend on, what an apply of paths needs.
+func (g *Graph) WithDependencies(paths ...string) (*Graph, error) {
+	return g.closure(paths, func(path string) []string { return g.Modules[path].Dependencies })
+}
+
+// WithDependents returns the subgraph of paths and everything depending on them, what a destroy of paths needs.
+func (g *Graph) WithDependents(paths ...string) (*Graph, error) {
+	return g.closure(paths, g.Dependents)
+}
+
+// Subtree returns the modules under prefix, dependencies outside of it are dropped from the result.
+func (g *Graph) Subtree(prefix string) (*Graph, error) {
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	var paths []string
+	for _, path := range g.Paths() {
+		if prefix == "." || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownModule, prefix)
+	}
+
+	return g.subgraph(paths), nil
+}
+
+func (g *Graph) closure(paths []string, next func(string) []string) (*Graph, error) {
+	seen := map[string]bool{}
+	queue := append([]string(nil), paths...)
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		if seen[path] {
+			continue
+		}
+		if _, ok := g.Modules[path]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownModule, path)
+		}
+		seen[path] = true
+		queue = append(queue, next(path)...)
+	}
+
+	selected := make([]string, 0, len(seen))
+	for path := range seen {
+		selected = append(selected, path)
+	}
+
+	return g.subgraph(selected), nil
+}
+
+// subgraph copies the selected modules and drops dependencies on modules that were not selected.
+func (g *Graph) subgraph(paths []string) *Graph {
+	selected := map[string]bool{}
+	for _, path := range paths {
+		selected[path] = true
+	}
+
+	sub := &Graph{Root: g.Root, Modules: map[string]*Module{}}
+	for path := range selected {
+		module := *g.Modules[path]
+		module.Dependencies = nil
+		for _, dependency := range g.Modules[path].Dependencies {
+			if selected[dependency] {
+				module.Dependencies = append(module.Dependencies, dependency)
+			}
+		}
+		sub.Modules[path] = &module
+	}
+
+	return sub
+}
+
+// DOT renders the graph in Graphviz format, edges point from a module to its dependencies.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph {\n")
+	for _, path := range g.Paths() {
+		fmt.Fprintf(&b, "\t%q ;\n", path)
+		for _, dependency := range g.Modules[path].Dependencies {
+			fmt.Fprintf(&b, "\t%q -> %q;\n", path, dependency)
+		}
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
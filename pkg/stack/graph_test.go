@@ -0,0 +1,90 @@
+package stack_test
+
+import (
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/pkg/stack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGraph(deps map[string][]string) *stack.Graph {
+	graph := &stack.Graph{Root: "/stack", Modules: map[string]*stack.Module{}}
+	for path, dependencies := range deps {
+		graph.Modules[path] = &stack.Module{Path: path, Dir: "/stack/" + path, Dependencies: dependencies}
+	}
+
+	return graph
+}
+
+func TestMockGraph_Order(t *testing.T) {
+	t.Parallel()
+	graph := newGraph(map[string][]string{
+		"vpc":  nil,
+		"iam":  nil,
+		"app":  {"vpc", "iam"},
+		"dns":  {"vpc"},
+		"edge": {"app", "dns"},
+	})
+
+	order, err := graph.TopologicalOrder()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"iam", "vpc", "app", "dns", "edge"}, order)
+	assert.Equal(t, []string{"iam", "vpc"}, graph.Roots())
+	assert.Equal(t, []string{"edge"}, graph.Leaves())
+	assert.Equal(t, []string{"app", "dns"}, graph.Dependents("vpc"))
+	assert.Empty(t, graph.Cycles())
+}
+
+func TestMockGraph_Cycles(t *testing.T) {
+	t.Parallel()
+	graph := newGraph(map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+		"d": {"d"},
+		"e": nil,
+	})
+
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"d"}}, graph.Cycles())
+
+	_, err := graph.TopologicalOrder()
+	require.ErrorIs(t, err, stack.ErrCycle)
+}
+
+func TestMockGraph_Subgraphs(t *testing.T) {
+	t.Parallel()
+	graph := newGraph(map[string][]string{
+		"net/vpc": nil,
+		"app/iam": nil,
+		"app/api": {"net/vpc", "app/iam"},
+		"app/web": {"app/api"},
+	})
+
+	apply, err := graph.WithDependencies("app/api")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app/api", "app/iam", "net/vpc"}, apply.Paths())
+
+	destroy, err := graph.WithDependents("app/iam")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app/api", "app/iam", "app/web"}, destroy.Paths())
+	assert.Equal(t, []string{"app/iam"}, destroy.Modules["app/api"].Dependencies)
+
+	subtree, err := graph.Subtree("app")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app/api", "app/iam", "app/web"}, subtree.Paths())
+
+	_, err = graph.WithDependencies("missing")
+	require.ErrorIs(t, err, stack.ErrUnknownModule)
+}
+
+func TestMockGraph_DOT(t *testing.T) {
+	t.Parallel()
+	graph := newGraph(map[string][]string{
+		"a": {"b"},
+		"b": nil,
+	})
+
+	assert.Equal(t, "digraph {\n\t\"a\" ;\n\t\"a\" -> \"b\";\n\t\"b\" ;\n}\n", graph.DOT())
+}
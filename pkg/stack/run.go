@@ -0,0 +1,157 @@
+package stack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// Runner applies and destroys a single module.
+type Runner interface {
+	ApplyE(t *testing.T, options *terraform.Options) (string, error)
+	DestroyE(t *testing.T, options *terraform.Options) (string, error)
+}
+
+// RealRunner runs terraform.ApplyE and terraform.DestroyE, plain options.TerraformBinary apply and destroy
+// in the module directory. It skips the account guard, cache lock, plugin cache init, retries and vars restore
+// of terragrunt.Apply and terragrunt.Destroy, terragrunt.StackRunner provides them.
+type RealRunner struct{}
+
+func (RealRunner) ApplyE(t *testing.T, options *terraform.Options) (string, error) {
+	return terraform.ApplyE(t, options)
+}
+
+func (RealRunner) DestroyE(t *testing.T, options *terraform.Options) (string, error) {
+	return terraform.DestroyE(t, options)
+}
+
+// Result is the outcome for one module.
+type Result struct {
+	Module   string
+	Output   string
+	Err      error
+	Skipped  bool
+	Duration time.Duration
+}
+
+// Results are in execution order.
+type Results []Result
+
+// Failed returns the modules that failed, skipped modules are not included.
+func (r Results) Failed() []string {
+	var failed []string
+	for _, result := range r {
+		if result.Err != nil {
+			failed = append(failed, result.Module)
+		}
+	}
+
+	return failed
+}
+
+// Err joins the errors of the failed modules, nil if every module succeeded.
+func (r Results) Err() error {
+	var errs []error
+	var skipped []string
+	for _, result := range r {
+		switch {
+		case result.Err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", result.Module, result.Err))
+		case result.Skipped:
+			skipped = append(skipped, result.Module)
+		}
+	}
+	if len(skipped) > 0 {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrSkipped, strings.Join(skipped, ", ")))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Apply applies the modules in topological order, options is copied for each module with its directory.
+// A module is skipped when one of its dependencies failed or was skipped.
+func Apply(t *testing.T, graph *Graph, options *terraform.Options, runner Runner) (Results, error) {
+	order, err := graph.TopologicalOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	results := run(t, graph, order, options, "apply", runner.ApplyE, func(path string) []string {
+		return graph.Modules[path].Dependencies
+	})
+
+	return results, results.Err()
+}
+
+// Destroy destroys the modules in reverse topological order.
+// A module is skipped when a module depending on it failed or was skipped, as it is still in use.
+func Destroy(t *testing.T, graph *Graph, options *terraform.Options, runner Runner) (Results, error) {
+	order, err := graph.TopologicalOrder()
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+
+	results := run(t, graph, order, options, "destroy", runner.DestroyE, graph.Dependents)
+
+	return results, results.Err()
+}
+
+func run(t *testing.T, graph *Graph, order []string, options *terraform.Options, action string,
+	fn func(*testing.T, *terraform.Options) (string, error), blockers func(string) []string) Results {
+	results := make(Results, 0, len(order))
+	blocked := map[string]bool{}
+
+	for _, unresolved := range graph.Unresolved() {
+		logger.Log(t, "Warning: skipped a path that could not be evaluated,", unresolved)
+	}
+
+	for _, path := range order {
+		result := Result{Module: path}
+
+		for _, blocker := range blockers(path) {
+			if blocked[blocker] {
+				result.Skipped = true
+
+				break
+			}
+		}
+		if result.Skipped {
+			logger.Log(t, "Skipping", action, "of", path, "after a failure")
+			blocked[path] = true
+			results = append(results, result)
+
+			continue
+		}
+
+		moduleOptions, err := options.Clone()
+		if err != nil {
+			result.Err = fmt.Errorf("failed to clone options: %w", err)
+			blocked[path] = true
+			results = append(results, result)
+
+			continue
+		}
+		moduleOptions.TerraformDir = graph.Modules[path].Dir
+
+		logger.Log(t, "TerraGrunt", action, "of", path, "in progress")
+		start := time.Now()
+		result.Output, result.Err = fn(t, moduleOptions)
+		result.Duration = time.Since(start)
+		if result.Err != nil {
+			blocked[path] = true
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+var ErrSkipped = errors.New("modules skipped after a failure")
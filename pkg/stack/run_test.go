@@ -0,0 +1,89 @@
+package stack_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/pkg/stack"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRunner struct {
+	mock.Mock
+}
+
+func (m *MockRunner) ApplyE(t *testing.T, options *terraform.Options) (string, error) {
+	args := m.Called(options.TerraformDir)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRunner) DestroyE(t *testing.T, options *terraform.Options) (string, error) {
+	args := m.Called(options.TerraformDir)
+
+	return args.String(0), args.Error(1)
+}
+
+func runGraph() *stack.Graph {
+	return newGraph(map[string][]string{
+		"vpc": nil,
+		"iam": nil,
+		"app": {"vpc"},
+		"web": {"app"},
+	})
+}
+
+func TestMockApply_Order(t *testing.T) {
+	t.Parallel()
+	runner := new(MockRunner)
+	runner.On("ApplyE", mock.Anything).Return("ok", nil)
+
+	results, err := stack.Apply(t, runGraph(), &terraform.Options{TerraformBinary: "terragrunt"}, runner)
+
+	require.NoError(t, err)
+	var dirs []string
+	for _, call := range runner.Calls {
+		dirs = append(dirs, call.Arguments.String(0))
+	}
+	assert.Equal(t, []string{"/stack/iam", "/stack/vpc", "/stack/app", "/stack/web"}, dirs)
+	assert.Len(t, results, 4)
+	assert.Empty(t, results.Failed())
+}
+
+func TestMockApply_SkipsDependents(t *testing.T) {
+	t.Parallel()
+	runner := new(MockRunner)
+	runner.On("ApplyE", "/stack/vpc").Return("", fmt.Errorf("Mocked error"))
+	runner.On("ApplyE", mock.Anything).Return("ok", nil)
+
+	results, err := stack.Apply(t, runGraph(), &terraform.Options{}, runner)
+
+	require.Error(t, err)
+	require.ErrorIs(t, err, stack.ErrSkipped)
+	assert.Contains(t, err.Error(), "vpc: Mocked error")
+	assert.Equal(t, []string{"vpc"}, results.Failed())
+	runner.AssertNotCalled(t, "ApplyE", "/stack/app")
+	runner.AssertNotCalled(t, "ApplyE", "/stack/web")
+	runner.AssertCalled(t, "ApplyE", "/stack/iam")
+}
+
+func TestMockDestroy_ReverseOrder(t *testing.T) {
+	t.Parallel()
+	runner := new(MockRunner)
+	runner.On("DestroyE", "/stack/app").Return("", fmt.Errorf("Mocked error"))
+	runner.On("DestroyE", mock.Anything).Return("ok", nil)
+
+	results, err := stack.Destroy(t, runGraph(), &terraform.Options{}, runner)
+
+	require.Error(t, err)
+	var modules []string
+	for _, result := range results {
+		modules = append(modules, result.Module)
+	}
+	assert.Equal(t, []string{"web", "app", "vpc", "iam"}, modules)
+	assert.True(t, results[2].Skipped)
+	runner.AssertNotCalled(t, "DestroyE", "/stack/vpc")
+}
@@ -0,0 +1,365 @@
+// Package stack discovers the modules of a terragrunt stack and the dependencies between them,
+// so modules can be applied and destroyed one by one in dependency order.
+package stack
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const configFile = "terragrunt.hcl"
+
+// Module is a directory of the stack with a terragrunt.hcl that is not only included by others.
+type Module struct {
+	// Path is relative to the stack root, with forward slashes.
+	Path string
+	// Dir is the absolute module directory.
+	Dir string
+	// Dependencies are the paths of modules inside the stack this module depends on.
+	Dependencies []string
+	// External are absolute directories of dependencies outside the stack root.
+	External []string
+	// Includes are the absolute paths of included files.
+	Includes []string
+	// Unresolved lists the include and dependency paths that could not be evaluated, for example because
+	// they call run_cmd. They are skipped, the graph misses what they point to.
+	Unresolved []string
+}
+
+// Graph is the dependency graph of a stack.
+type Graph struct {
+	Root    string
+	Modules map[string]*Module
+}
+
+// Discover parses every terragrunt.hcl under root and builds the dependency graph.
+// Files that are included by other modules, like the root terragrunt.hcl, are not modules.
+func Discover(root string) (*Graph, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := configDirs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	graph, err := parseModules(root, dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, module := range graph.Modules {
+		for _, dependency := range module.Dependencies {
+			if _, ok := graph.Modules[dependency]; !ok {
+				return nil, fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, module.Path, dependency)
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// parseModules resolves the includes and dependencies of the configs in dirs, configs included by
+// another one are dropped.
+func parseModules(root string, dirs []string) (*Graph, error) {
+	graph := &Graph{Root: root, Modules: map[string]*Module{}}
+	included := map[string]bool{}
+	var errs []error
+
+	for _, dir := range dirs {
+		module, err := parseModule(root, dir)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+		for _, include := range module.Includes {
+			included[include] = true
+		}
+		graph.Modules[module.Path] = module
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	for path, module := range graph.Modules {
+		if included[filepath.Join(module.Dir, configFile)] {
+			delete(graph.Modules, path)
+		}
+	}
+	if len(graph.Modules) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoModules, root)
+	}
+
+	return graph, nil
+}
+
+// Unresolved lists the paths of every module that could not be evaluated, prefixed by the module.
+func (g *Graph) Unresolved() []string {
+	var unresolved []string
+	for _, path := range g.Paths() {
+		for _, expr := range g.Modules[path].Unresolved {
+			unresolved = append(unresolved, path+": "+expr)
+		}
+	}
+
+	return unresolved
+}
+
+// FindModules returns the paths of the modules under root, relative to it with forward slashes and sorted.
+// Unlike Discover it only walks the tree and evaluates no config. A directory with modules below it is
+// taken for their parent config, like the root terragrunt.hcl the modules include.
+func FindModules(root string) ([]string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := configDirs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, dir := range dirs {
+		if slices.ContainsFunc(dirs, func(other string) bool {
+			return strings.HasPrefix(other, dir+string(filepath.Separator))
+		}) {
+			continue
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%w in %s", ErrNoModules, root)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// configDirs walks root for the directories holding a terragrunt.hcl, downloaded code is skipped.
+func configDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && core.SkippedDir(entry.Name()) {
+			return filepath.SkipDir
+		}
+		if !entry.IsDir() && entry.Name() == configFile {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover modules in %s: %w", root, err)
+	}
+
+	return dirs, nil
+}
+
+// parseModule reads the includes and dependencies of the terragrunt.hcl in dir.
+// Dependencies declared in included files, such as _envcommon, count for the module.
+func parseModule(root, dir string) (*Module, error) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	module := &Module{Path: filepath.ToSlash(rel), Dir: dir}
+
+	body, err := parseFile(filepath.Join(dir, configFile))
+	if err != nil {
+		return nil, err
+	}
+
+	includes, unresolved := includePaths(dir, body)
+	module.Includes = includes
+	module.Unresolved = unresolved
+
+	bodies := []*hclsyntax.Body{body}
+	for _, include := range includes {
+		included, err := parseFile(include)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, included)
+	}
+
+	seen := map[string]bool{}
+	for _, body := range bodies {
+		paths, unresolved := dependencyPaths(dir, body)
+		module.Unresolved = append(module.Unresolved, unresolved...)
+		for _, path := range paths {
+			path = absPath(dir, path)
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				module.External = append(module.External, path)
+
+				continue
+			}
+			module.Dependencies = append(module.Dependencies, filepath.ToSlash(rel))
+		}
+	}
+	sort.Strings(module.Dependencies)
+	sort.Strings(module.External)
+
+	return module, nil
+}
+
+func parseFile(path string) (*hclsyntax.Body, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("readFile func failed to read %s: %w", path, err)
+	}
+
+	file, diags := hclsyntax.ParseConfig(content, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, diags)
+	}
+
+	return file.Body.(*hclsyntax.Body), nil
+}
+
+// includePaths evaluates the path of every include block, paths that cannot be evaluated are returned apart.
+func includePaths(dir string, body *hclsyntax.Body) ([]string, []string) {
+	ctx := evalContext(dir, localsOf(dir, body))
+
+	var paths, unresolved []string
+	for _, block := range body.Blocks {
+		if block.Type != "include" {
+			continue
+		}
+		attr, ok := block.Body.Attributes["path"]
+		if !ok {
+			continue
+		}
+
+		path, err := evalString(attr.Expr, ctx)
+		if err != nil {
+			unresolved = append(unresolved, err.Error())
+
+			continue
+		}
+		paths = append(paths, absPath(dir, path))
+	}
+
+	return paths, unresolved
+}
+
+// dependencyPaths evaluates config_path of dependency blocks and paths of the dependencies block,
+// paths that cannot be evaluated are returned apart.
+func dependencyPaths(dir string, body *hclsyntax.Body) ([]string, []string) {
+	ctx := evalContext(dir, localsOf(dir, body))
+
+	var paths, unresolved []string
+	for _, block := range body.Blocks {
+		var found []string
+		var err error
+		switch block.Type {
+		case "dependency":
+			found, err = dependencyConfigPath(block, ctx)
+		case "dependencies":
+			found, err = dependenciesPaths(block, ctx)
+		}
+		if err != nil {
+			unresolved = append(unresolved, err.Error())
+
+			continue
+		}
+		paths = append(paths, found...)
+	}
+
+	return paths, unresolved
+}
+
+func dependencyConfigPath(block *hclsyntax.Block, ctx *hcl.EvalContext) ([]string, error) {
+	attr, ok := block.Body.Attributes["config_path"]
+	if !ok {
+		return nil, nil
+	}
+	path, err := evalString(attr.Expr, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{path}, nil
+}
+
+func dependenciesPaths(block *hclsyntax.Block, ctx *hcl.EvalContext) ([]string, error) {
+	attr, ok := block.Body.Attributes["paths"]
+	if !ok {
+		return nil, nil
+	}
+	value, diags := attr.Expr.Value(ctx)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	if !value.CanIterateElements() || !value.IsWhollyKnown() {
+		return nil, fmt.Errorf("%s: dependencies paths is not a list", attr.SrcRange)
+	}
+
+	var paths []string
+	for it := value.ElementIterator(); it.Next(); {
+		_, element := it.Element()
+		if element.Type() != cty.String || element.IsNull() {
+			return nil, fmt.Errorf("%s: dependencies paths must be strings", attr.SrcRange)
+		}
+		paths = append(paths, element.AsString())
+	}
+
+	return paths, nil
+}
+
+func localsOf(dir string, body *hclsyntax.Body) map[string]cty.Value {
+	for _, block := range body.Blocks {
+		if block.Type == "locals" {
+			return evalLocals(dir, block.Body.Attributes)
+		}
+	}
+
+	return map[string]cty.Value{}
+}
+
+func evalString(expr hclsyntax.Expression, ctx *hcl.EvalContext) (string, error) {
+	value, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	if value.Type() != cty.String || value.IsNull() || !value.IsKnown() {
+		return "", fmt.Errorf("%s: expected a string path", expr.Range())
+	}
+
+	return value.AsString(), nil
+}
+
+var (
+	ErrNoModules         = errors.New("no terragrunt modules found")
+	ErrUnknownDependency = errors.New("dependency is not a module of the stack")
+	ErrCycle             = errors.New("dependency cycle")
+	ErrUnknownModule     = errors.New("unknown module")
+)
@@ -0,0 +1,185 @@
+package stack_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/pkg/stack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	return root
+}
+
+const childConfig = `
+include "root" {
+  path = find_in_parent_folders()
+}
+`
+
+func testStack(t *testing.T) string {
+	t.Helper()
+
+	return writeFiles(t, map[string]string{
+		"terragrunt.hcl": `remote_state {}`,
+		"_envcommon/app.hcl": `
+dependency "vpc" {
+  config_path = "${get_terragrunt_dir()}/../vpc"
+}
+`,
+		"app/vpc/terragrunt.hcl": childConfig,
+		"app/iam/terragrunt.hcl": childConfig + `
+include "envcommon" {
+  path = "${dirname(find_in_parent_folders())}/_envcommon/app.hcl"
+}
+`,
+		"app/iam2/terragrunt.hcl": childConfig + `
+locals {
+  iam = "../iam"
+}
+
+dependencies {
+  paths = [local.iam, "../vpc"]
+}
+`,
+		"app/iam/.terragrunt-cache/x/terragrunt.hcl": `broken {`,
+	})
+}
+
+func TestMockDiscover(t *testing.T) {
+	t.Parallel()
+	root := testStack(t)
+
+	graph, err := stack.Discover(root)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"app/iam", "app/iam2", "app/vpc"}, graph.Paths())
+	assert.Equal(t, []string{"app/vpc"}, graph.Modules["app/iam"].Dependencies)
+	assert.Equal(t, []string{"app/iam", "app/vpc"}, graph.Modules["app/iam2"].Dependencies)
+	assert.Contains(t, graph.Modules["app/iam"].Includes, filepath.Join(root, "_envcommon", "app.hcl"))
+}
+
+func TestMockDiscover_Subdir(t *testing.T) {
+	t.Parallel()
+	root := testStack(t)
+
+	graph, err := stack.Discover(filepath.Join(root, "app"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"iam", "iam2", "vpc"}, graph.Paths())
+}
+
+func TestMockDiscover_External(t *testing.T) {
+	t.Parallel()
+	root := testStack(t)
+
+	graph, err := stack.Discover(filepath.Join(root, "app", "iam"))
+	require.NoError(t, err)
+
+	assert.Empty(t, graph.Modules["."].Dependencies)
+	assert.Equal(t, []string{filepath.Join(root, "app", "vpc")}, graph.Modules["."].External)
+}
+
+func TestMockDiscover_UnknownDependency(t *testing.T) {
+	t.Parallel()
+	root := writeFiles(t, map[string]string{
+		"a/terragrunt.hcl": `dependency "b" { config_path = "../b" }`,
+		"b/readme.md":      ``,
+	})
+
+	_, err := stack.Discover(root)
+
+	require.ErrorIs(t, err, stack.ErrUnknownDependency)
+}
+
+func TestMockDiscover_Example(t *testing.T) {
+	t.Parallel()
+
+	graph, err := stack.Discover("../../example")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"app/iam", "app/iam2"}, graph.Paths())
+}
+
+func TestMockFindModules(t *testing.T) {
+	t.Parallel()
+	root := testStack(t)
+	// The walk evaluates nothing, a function Discover does not know is no problem
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app", "vpc", "terragrunt.hcl"),
+		[]byte(`dependency "x" { config_path = run_cmd("echo", "../iam") }`), 0644))
+
+	paths, err := stack.FindModules(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app/iam", "app/iam2", "app/vpc"}, paths)
+
+	paths, err = stack.FindModules(filepath.Join(root, "app", "iam"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"."}, paths)
+
+	_, err = stack.FindModules(filepath.Join(root, "_envcommon"))
+	require.ErrorIs(t, err, stack.ErrNoModules)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockDiscover_Functions(t *testing.T) {
+	t.Setenv("TT_STACK_VPC", "vpc")
+	root := writeFiles(t, map[string]string{
+		".git/HEAD":      `ref: refs/heads/main`,
+		"terragrunt.hcl": `remote_state {}`,
+		"app/vpc/terragrunt.hcl": `
+include "root" {
+  path = "${get_repo_root()}/terragrunt.hcl"
+}
+`,
+		"app/iam/terragrunt.hcl": `
+include "root" {
+  path = "${get_path_to_repo_root()}/terragrunt.hcl"
+}
+
+dependency "vpc" {
+  config_path = "../${get_env("TT_STACK_VPC")}"
+}
+
+dependency "db" {
+  config_path = "../${get_env("TT_STACK_DB", "vpc")}"
+}
+`,
+	})
+
+	graph, err := stack.Discover(root)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"app/iam", "app/vpc"}, graph.Paths())
+	assert.Equal(t, []string{"app/vpc"}, graph.Modules["app/iam"].Dependencies)
+	assert.Empty(t, graph.Unresolved())
+}
+
+func TestMockDiscover_Unresolved(t *testing.T) {
+	t.Parallel()
+	root := testStack(t)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app", "vpc", "terragrunt.hcl"), []byte(childConfig+`
+dependency "iam" {
+  config_path = run_cmd("echo", "../iam")
+}
+`), 0644))
+
+	// The dependency is skipped instead of failing the discovery
+	graph, err := stack.Discover(root)
+	require.NoError(t, err)
+
+	assert.Empty(t, graph.Modules["app/vpc"].Dependencies)
+	require.Len(t, graph.Unresolved(), 1)
+	assert.Contains(t, graph.Unresolved()[0], "app/vpc: ")
+	assert.Contains(t, graph.Unresolved()[0], "run_cmd")
+}
@@ -0,0 +1,220 @@
+package terragrunt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+)
+
+const (
+	PhaseInit        = "init"
+	PhaseApply       = "apply"
+	PhaseDestroyInit = "destroy init"
+	PhaseDestroy     = "destroy"
+	PhaseOutput      = "output"
+	PhasePlan        = "plan"
+)
+
+// defaultKillGrace is used when RealCommandExecutor.KillGrace is not set.
+const defaultKillGrace = 10 * time.Second
+
+// ContextCommandExecutor is a CommandExecutor that can be cancelled.
+// Executors implementing only CommandExecutor keep working, their commands are just not cancelled.
+type ContextCommandExecutor interface {
+	CommandExecutor
+	RunCommandContext(ctx context.Context, cmdName string, args []string, dir string, envVars map[string]string) ([]byte, error)
+}
+
+// RunCommandContext runs the command in its own process group. When ctx is done the group gets SIGINT,
+// so terraform can release state locks, and SIGKILL once KillGrace has passed.
+// Output is logged while the command runs when ctx comes from WithLogStream.
+func (e *RealCommandExecutor) RunCommandContext(ctx context.Context, cmdName string, args []string, dir string, envVars map[string]string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, cmdName, args...)
+	cmd.Dir = dir
+
+	// Prepare environment variables.
+	cmd.Env = os.Environ() // Start with existing environment variables.
+	for key, value := range envVars {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	grace := e.KillGrace
+	if grace <= 0 {
+		grace = defaultKillGrace
+	}
+
+	var mu sync.Mutex
+	var kill *time.Timer
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		kill = time.AfterFunc(grace, func() { killProcessGroup(cmd) })
+
+		return interruptProcessGroup(cmd)
+	}
+	// Give the group kill a head start before exec stops waiting for the output pipes.
+	cmd.WaitDelay = grace + time.Second
+
+	var output []byte
+	var err error
+	if target, ok := streamFrom(ctx); ok {
+		// Stdout and stderr share the writer, exec then copies them from a single pipe.
+		stream := &lineLogger{target: target}
+		cmd.Stdout = stream
+		cmd.Stderr = stream
+		err = cmd.Run()
+		stream.Flush()
+		output = stream.buf.Bytes()
+	} else if stdoutOnly(ctx) {
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+		output = stdout.Bytes()
+		if err != nil {
+			err = fmt.Errorf("%w\nStderr:\n%s", err, stderr.Bytes())
+		}
+	} else {
+		output, err = cmd.CombinedOutput()
+	}
+
+	mu.Lock()
+	if kill != nil {
+		kill.Stop()
+	}
+	mu.Unlock()
+
+	return output, err
+}
+
+type commandExecutorKey struct{}
+
+// withCommandExecutor passes the CommandExecutor of Apply, Destroy and Plan on to RealTerragruntExecutor.
+func withCommandExecutor(ctx context.Context, executor CommandExecutor) context.Context {
+	if executor == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, commandExecutorKey{}, executor)
+}
+
+// commandExecutorFrom returns the executor passed with withCommandExecutor, a RealCommandExecutor otherwise.
+func commandExecutorFrom(ctx context.Context) CommandExecutor {
+	if executor, ok := ctx.Value(commandExecutorKey{}).(CommandExecutor); ok {
+		return executor
+	}
+
+	return &RealCommandExecutor{}
+}
+
+// runCommandContext cancels the command with ctx when executor supports it.
+func runCommandContext(ctx context.Context, executor CommandExecutor, cmdName string, args []string, dir string,
+	envVars map[string]string) ([]byte, error) {
+	if ctxExecutor, ok := executor.(ContextCommandExecutor); ok {
+		return ctxExecutor.RunCommandContext(ctx, cmdName, args, dir, envVars)
+	}
+
+	return executor.RunCommand(cmdName, args, dir, envVars)
+}
+
+type stdoutKey struct{}
+
+// withStdoutOnly makes RealCommandExecutor.RunCommandContext return stdout alone, for output that is parsed.
+// Stderr is only added to the error.
+func withStdoutOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stdoutKey{}, true)
+}
+
+func stdoutOnly(ctx context.Context) bool {
+	only, _ := ctx.Value(stdoutKey{}).(bool)
+
+	return only
+}
+
+// PhaseTimeoutError reports a command cancelled because the test deadline was near.
+type PhaseTimeoutError struct {
+	Phase    string
+	Dir      string
+	Deadline time.Time
+}
+
+func (e *PhaseTimeoutError) Error() string {
+	return fmt.Sprintf("terragrunt %s in %s timed out at %s", e.Phase, e.Dir, e.Deadline.Format(time.RFC3339))
+}
+
+func (e *PhaseTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// phaseContext ends at the test deadline minus config.DestroyMargin. Destroy phases may use the margin
+// up to config.CleanupMargin, which is left for restoring the vars file.
+func phaseContext(t *testing.T, config core.RunTime, phase string) (context.Context, context.CancelFunc) {
+	deadline, ok := t.Deadline()
+	if !ok {
+		return context.WithCancel(context.Background())
+	}
+
+	if phase == PhaseDestroyInit || phase == PhaseDestroy {
+		deadline = deadline.Add(-config.CleanupMargin())
+	} else {
+		deadline = deadline.Add(-config.DestroyMargin)
+	}
+
+	return context.WithDeadline(context.Background(), deadline)
+}
+
+// runPhase runs fn with the deadline of phase and its output streamed, a cancelled run is reported as a
+// PhaseTimeoutError. RealTerragruntExecutor runs its commands through cmdExecutor.
+func runPhase(t *testing.T, config core.RunTime, phase, dir string, cmdExecutor CommandExecutor,
+	fn func(ctx context.Context) (string, error)) (string, error) {
+	ctx, cancel := phaseContext(t, config, phase)
+	defer cancel()
+
+	output, err := fn(withCommandExecutor(WithLogStream(ctx, t, dir, phase), cmdExecutor))
+	if ctx.Err() != nil {
+		deadline, _ := ctx.Deadline()
+
+		return output, &PhaseTimeoutError{Phase: phase, Dir: dir, Deadline: deadline}
+	}
+
+	return output, err
+}
+
+// runCommand runs the command with the deadline of phase and streams its output when executor supports it.
+// streamed reports whether the output was already logged.
+func runCommand(t *testing.T, config core.RunTime, executor CommandExecutor, phase string,
+	cmdName string, args []string, dir string, envVars map[string]string) (output []byte, streamed bool, err error) {
+	ctxExecutor, ok := executor.(ContextCommandExecutor)
+	if !ok {
+		output, err = executor.RunCommand(cmdName, args, dir, envVars)
+
+		return output, false, err
+	}
+
+	ctx, cancel := phaseContext(t, config, phase)
+	defer cancel()
+	// Outputs may hold sensitive values and are not logged, they are parsed from stdout alone.
+	streamed = phase != PhaseOutput
+	if streamed {
+		ctx = WithLogStream(ctx, t, dir, phase)
+	} else {
+		ctx = withStdoutOnly(ctx)
+	}
+
+	output, err = ctxExecutor.RunCommandContext(ctx, cmdName, args, dir, envVars)
+	if ctx.Err() != nil {
+		deadline, _ := ctx.Deadline()
+
+		return output, streamed, &PhaseTimeoutError{Phase: phase, Dir: dir, Deadline: deadline}
+	}
+
+	return output, streamed, err
+}
@@ -0,0 +1,23 @@
+//go:build !unix
+
+package terragrunt
+
+import (
+	"os"
+	"os/exec"
+)
+
+func setProcessGroup(_ *exec.Cmd) {}
+
+// interruptProcessGroup falls back to the terragrunt process, there are no process groups.
+func interruptProcessGroup(cmd *exec.Cmd) error {
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		return cmd.Process.Kill()
+	}
+
+	return nil
+}
+
+func killProcessGroup(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
@@ -0,0 +1,204 @@
+package terragrunt_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/terragrunt"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockContextCommandExecutor blocks until the context is done.
+type MockContextCommandExecutor struct {
+	MockCommandExecutor
+}
+
+func (m *MockContextCommandExecutor) RunCommandContext(ctx context.Context, _ string, _ []string, _ string, _ map[string]string) ([]byte, error) {
+	<-ctx.Done()
+
+	return []byte("interrupted"), ctx.Err()
+}
+
+// MockContextExecutor runs apply, destroy and plan until the context is done.
+type MockContextExecutor struct {
+	MockTerragruntExecutor
+}
+
+func (m *MockContextExecutor) TgApplyAllContext(ctx context.Context, _ *testing.T, _ *terraform.Options) (string, error) {
+	<-ctx.Done()
+
+	return "interrupted", ctx.Err()
+}
+
+func (m *MockContextExecutor) TgDestroyAllContext(ctx context.Context, _ *testing.T, _ *terraform.Options) (string, error) {
+	<-ctx.Done()
+
+	return "interrupted", ctx.Err()
+}
+
+func (m *MockContextExecutor) TgPlanAllContext(ctx context.Context, _ *testing.T, _ *terraform.Options) (string, int, error) {
+	<-ctx.Done()
+
+	return "interrupted", terraform.DefaultErrorExitCode, ctx.Err()
+}
+
+func TestMockRunCommandContext_KillsProcessGroup(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are unix only")
+	}
+
+	executor := &terragrunt.RealCommandExecutor{KillGrace: 200 * time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// The shell ignores SIGINT and its child keeps the output pipe open, only SIGKILL to the group ends both.
+	start := time.Now()
+	_, err := executor.RunCommandContext(ctx, "sh", []string{"-c", `trap "" INT; sleep 30 & wait`}, "", nil)
+
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestMockRunCommandContext_Success(t *testing.T) {
+	t.Parallel()
+	executor := &terragrunt.RealCommandExecutor{}
+
+	output, err := executor.RunCommand("sh", []string{"-c", "echo $TT_TEST_VALUE"}, "", map[string]string{"TT_TEST_VALUE": "ok"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok\n", string(output))
+}
+
+func TestMockTgApply_InitTimeout(t *testing.T) {
+	t.Parallel()
+	if _, ok := t.Deadline(); !ok {
+		t.Skip("test runs without a deadline")
+	}
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockContextCommandExecutor)
+
+	// A margin longer than any test timeout puts the init deadline in the past.
+	config := core.RunTime{IsPluginCache: true, DestroyMargin: 1000 * time.Hour}
+	err := terragrunt.Apply(t, &terraform.Options{TerraformDir: "../../example"}, mockExecutor, config, cmdMockExecutor)
+
+	var timeout *terragrunt.PhaseTimeoutError
+	require.ErrorAs(t, err, &timeout)
+	assert.Equal(t, terragrunt.PhaseInit, timeout.Phase)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestMockRunCommandContext_Stream(t *testing.T) {
+	t.Parallel()
+	executor := &terragrunt.RealCommandExecutor{}
+	root, err := filepath.Abs("../../example")
+	require.NoError(t, err)
+	mockLogger := new(MockLogger)
+	ctx := terragrunt.WithLogStream(context.Background(), t, "../../example", terragrunt.PhaseInit)
+	ctx = terragrunt.WithStreamLogger(ctx, mockLogger)
+
+	// Each line is logged with the module terragrunt prefixed it with, relative to the stack dir.
+	mockLogger.On("Log", t, "[app/iam] [init] one").Once()
+	mockLogger.On("Log", t, "[app/vpc] [init] two").Once()
+	mockLogger.On("Log", t, "[app/iam] [init] level=info msg=three prefix=["+root+"/app/iam]").Once()
+	mockLogger.On("Log", t, "[.] [init] four").Once()
+
+	script := "echo '[app/iam] one'; echo '[app/vpc] two' >&2; echo 'level=info msg=three prefix=[" + root +
+		"/app/iam]'; printf four"
+	output, err := executor.RunCommandContext(ctx, "sh", []string{"-c", script}, "", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "[app/iam] one\n[app/vpc] two\nlevel=info msg=three prefix=["+root+"/app/iam]\nfour", string(output))
+	mockLogger.AssertExpectations(t)
+}
+
+func TestMockTgApplyDestroy_Timeout(t *testing.T) {
+	t.Parallel()
+	if _, ok := t.Deadline(); !ok {
+		t.Skip("test runs without a deadline")
+	}
+	mockExecutor := new(MockContextExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	// A margin longer than any test timeout puts both deadlines in the past, destroy keeps a tenth of it.
+	config := core.RunTime{DestroyMargin: 1000 * time.Hour, Retry: core.RetryPolicy{MaxAttempts: 3}}
+	options := &terraform.Options{TerraformDir: "../../example"}
+
+	var timeout *terragrunt.PhaseTimeoutError
+	err := terragrunt.Apply(t, options, mockExecutor, config, cmdMockExecutor)
+	require.ErrorAs(t, err, &timeout)
+	assert.Equal(t, terragrunt.PhaseApply, timeout.Phase)
+
+	err = terragrunt.Destroy(t, options, mockExecutor, config, cmdMockExecutor, false)
+	require.ErrorAs(t, err, &timeout)
+	assert.Equal(t, terragrunt.PhaseDestroy, timeout.Phase)
+	deadline, _ := t.Deadline()
+	assert.Equal(t, deadline.Add(-config.CleanupMargin()), timeout.Deadline)
+}
+
+func TestMockTgPlan_Timeout(t *testing.T) {
+	t.Parallel()
+	if _, ok := t.Deadline(); !ok {
+		t.Skip("test runs without a deadline")
+	}
+	mockExecutor := new(MockContextExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	// Plan stops at the deadline of apply, the margin is left for destroy.
+	config := core.RunTime{DestroyMargin: 1000 * time.Hour}
+	summary, err := terragrunt.Plan(t, &terraform.Options{TerraformDir: "../../example"}, mockExecutor, config, cmdMockExecutor)
+
+	var timeout *terragrunt.PhaseTimeoutError
+	require.ErrorAs(t, err, &timeout)
+	assert.Equal(t, terragrunt.PhasePlan, timeout.Phase)
+	assert.Equal(t, terraform.DefaultErrorExitCode, summary.ExitCode)
+	mockExecutor.AssertNotCalled(t, "TgPlanAllE", t, mock.Anything)
+}
+
+func TestMockTgApply_CommandExecutor(t *testing.T) {
+	t.Parallel()
+	executor := &terragrunt.RealTerragruntExecutor{}
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	// The real executor runs terragrunt through the command executor passed to Apply
+	cmdMockExecutor.On("RunCommand", "terragrunt", mock.MatchedBy(func(args []string) bool {
+		return len(args) > 1 && args[0] == "run-all" && args[1] == "apply"
+	}), "../../example", mock.Anything).Return([]byte("Apply complete!"), nil)
+
+	options := &terraform.Options{TerraformDir: "../../example", TerraformBinary: "terragrunt"}
+	err := terragrunt.Apply(t, options, executor, core.RunTime{}, cmdMockExecutor)
+
+	require.NoError(t, err)
+	cmdMockExecutor.AssertExpectations(t)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockTgPlan_RealExecutor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake terragrunt is a shell script")
+	}
+
+	// The fake terragrunt plans a change and exits with 2 like terraform plan -detailed-exitcode.
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho '[app/iam] Plan: 0 to import, 1 to add, 0 to change, 0 to destroy.'\nexit 2\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "terragrunt"), []byte(script), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	options := &terraform.Options{TerraformDir: "../../example", TerraformBinary: "terragrunt"}
+	summary, err := terragrunt.Plan(t, options, &terragrunt.RealTerragruntExecutor{}, core.RunTime{},
+		&terragrunt.RealCommandExecutor{KillGrace: time.Second})
+
+	require.NoError(t, err)
+	assert.Equal(t, terraform.TerraformPlanChangesPresentExitCode, summary.ExitCode)
+	assert.Equal(t, 1, summary.Modules["app/iam"].Add)
+}
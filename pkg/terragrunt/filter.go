@@ -0,0 +1,228 @@
+package terragrunt
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/stack"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/mattn/go-zglob"
+)
+
+// Filter limits a run-all to some modules of the stack, globs are relative to options.TerraformDir and
+// support ** like terragrunt's. A glob selects the modules whose dir it matches, not the modules below it.
+type Filter struct {
+	IncludeDirs []string
+	ExcludeDirs []string
+	// StrictInclude skips the dependencies of included modules.
+	StrictInclude bool
+}
+
+// IsEmpty reports whether the filter selects the whole stack.
+func (f Filter) IsEmpty() bool {
+	return len(f.IncludeDirs) == 0 && len(f.ExcludeDirs) == 0
+}
+
+// Select returns the modules of graph the filter runs, it fails on a glob matching no module
+// or a filter selecting nothing.
+func (f Filter) Select(graph *stack.Graph) ([]string, error) {
+	return f.selectModules(graph.Root, graph.Paths(), graph)
+}
+
+// selectModules matches the filter against modules, the paths under root. The dependencies of included
+// modules are only added with graph, without it the selection is what the globs match.
+func (f Filter) selectModules(root string, modules []string, graph *stack.Graph) ([]string, error) {
+	selected, errs := f.included(root, modules, graph)
+	for _, pattern := range f.ExcludeDirs {
+		matches, err := matchModules(root, modules, pattern)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+		for _, module := range matches {
+			delete(selected, module)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	var result []string
+	for _, module := range modules {
+		if selected[module] {
+			result = append(result, module)
+		}
+	}
+	if len(result) == 0 {
+		return nil, ErrEmptySelection
+	}
+
+	return result, nil
+}
+
+// included returns the modules selected by IncludeDirs, all of them without include globs.
+func (f Filter) included(root string, modules []string, graph *stack.Graph) (map[string]bool, []error) {
+	var errs []error
+
+	selected := map[string]bool{}
+	if len(f.IncludeDirs) == 0 {
+		for _, module := range modules {
+			selected[module] = true
+		}
+	}
+	for _, pattern := range f.IncludeDirs {
+		matches, err := matchModules(root, modules, pattern)
+		if err == nil && graph != nil && !f.StrictInclude {
+			// terragrunt also runs the dependencies of included modules.
+			var sub *stack.Graph
+			if sub, err = graph.WithDependencies(matches...); err == nil {
+				matches = sub.Paths()
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+		for _, module := range matches {
+			selected[module] = true
+		}
+	}
+
+	return selected, errs
+}
+
+// envVars passes the filter to terragrunt, which reads every flag from TERRAGRUNT_* variables.
+func (f Filter) envVars() map[string]string {
+	envVars := map[string]string{}
+	if len(f.IncludeDirs) > 0 {
+		envVars["TERRAGRUNT_INCLUDE_DIR"] = strings.Join(f.IncludeDirs, ",")
+	}
+	if len(f.ExcludeDirs) > 0 {
+		envVars["TERRAGRUNT_EXCLUDE_DIR"] = strings.Join(f.ExcludeDirs, ",")
+	}
+	if f.StrictInclude {
+		envVars["TERRAGRUNT_STRICT_INCLUDE"] = "true"
+	}
+
+	return envVars
+}
+
+// matchModules returns the modules matching pattern the way terragrunt matches --terragrunt-include-dir:
+// a relative pattern is joined to the stack root, ** spans directories and a module only matches with its own dir.
+func matchModules(root string, modules []string, pattern string) ([]string, error) {
+	glob := pattern
+	if !filepath.IsAbs(glob) {
+		glob = filepath.Join(root, glob)
+	}
+
+	var matches []string
+	for _, module := range modules {
+		ok, err := zglob.Match(glob, filepath.Join(root, filepath.FromSlash(module)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+		if ok {
+			matches = append(matches, module)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnmatchedFilter, pattern)
+	}
+
+	return matches, nil
+}
+
+// filterOptions validates filter against the stack under options.TerraformDir and returns options running only
+// the selected modules. The globs are matched against a walk of the stack, the dependency graph is only read to
+// list the dependencies of included modules.
+func filterOptions(t *testing.T, options *terraform.Options, filter Filter) (*terraform.Options, error) {
+	if filter.IsEmpty() {
+		return options, nil
+	}
+
+	root, err := filepath.Abs(options.TerraformDir)
+	if err != nil {
+		return nil, err
+	}
+	modules, err := stack.FindModules(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover modules: %w", err)
+	}
+
+	var graph *stack.Graph
+	if len(filter.IncludeDirs) > 0 && !filter.StrictInclude {
+		graph = discoverDependencies(t, root)
+		if graph != nil {
+			modules = graph.Paths()
+		}
+	}
+
+	modules, err = filter.selectModules(root, modules, graph)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	logger.Log(t, "Filter selected modules:", strings.Join(modules, ", "))
+
+	filtered, err := options.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if filtered.EnvVars == nil {
+		filtered.EnvVars = map[string]string{}
+	}
+	for key, value := range filter.envVars() {
+		filtered.EnvVars[key] = value
+	}
+
+	return filtered, nil
+}
+
+// discoverDependencies returns the dependency graph under root, or nil when it cannot be built. terragrunt
+// still runs the dependencies then, they are just not listed.
+func discoverDependencies(t *testing.T, root string) *stack.Graph {
+	graph, err := stack.Discover(root)
+	if err != nil {
+		logger.Log(t, "Warning: the dependencies of the included modules are not listed:", err)
+
+		return nil
+	}
+	for _, unresolved := range graph.Unresolved() {
+		logger.Log(t, "Warning: skipped a path that could not be evaluated,", unresolved)
+	}
+
+	return graph
+}
+
+// ApplyWithFilter runs Apply on the modules selected by filter.
+func ApplyWithFilter(t *testing.T, options *terraform.Options, executor Executor, config core.RunTime, cmdExecutor CommandExecutor, filter Filter) error {
+	filtered, err := filterOptions(t, options, filter)
+	if err != nil {
+
+		return err
+	}
+
+	return Apply(t, filtered, executor, config, cmdExecutor)
+}
+
+// DestroyWithFilter runs Destroy on the modules selected by filter.
+func DestroyWithFilter(t *testing.T, options *terraform.Options, executor Executor, config core.RunTime, cmdExecutor CommandExecutor, restore bool, filter Filter) error {
+	filtered, err := filterOptions(t, options, filter)
+	if err != nil {
+
+		return err
+	}
+
+	return Destroy(t, filtered, executor, config, cmdExecutor, restore)
+}
+
+var (
+	ErrUnmatchedFilter = errors.New("filter matches no module")
+	ErrEmptySelection  = errors.New("filter selects no module")
+)
@@ -0,0 +1,142 @@
+package terragrunt_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/stack"
+	"github.com/GoGstickGo/terratest-helpers/pkg/terragrunt"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func filterGraph() *stack.Graph {
+	return &stack.Graph{Root: "/stack", Modules: map[string]*stack.Module{
+		"app/iam":  {Path: "app/iam"},
+		"app/iam2": {Path: "app/iam2", Dependencies: []string{"net/vpc"}},
+		"net/vpc":  {Path: "net/vpc"},
+	}}
+}
+
+func TestMockFilterSelect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		filter terragrunt.Filter
+		want   []string
+	}{
+		{"empty", terragrunt.Filter{}, []string{"app/iam", "app/iam2", "net/vpc"}},
+		{"include", terragrunt.Filter{IncludeDirs: []string{"app/iam"}}, []string{"app/iam"}},
+		{"include with dependencies", terragrunt.Filter{IncludeDirs: []string{"app/iam2"}}, []string{"app/iam2", "net/vpc"}},
+		{"strict include", terragrunt.Filter{IncludeDirs: []string{"app/iam2"}, StrictInclude: true}, []string{"app/iam2"}},
+		{"dir glob", terragrunt.Filter{IncludeDirs: []string{"app/*"}, StrictInclude: true}, []string{"app/iam", "app/iam2"}},
+		{"any depth", terragrunt.Filter{IncludeDirs: []string{"**/vpc"}}, []string{"net/vpc"}},
+		{"absolute", terragrunt.Filter{IncludeDirs: []string{"/stack/net/vpc"}}, []string{"net/vpc"}},
+		{"exclude", terragrunt.Filter{ExcludeDirs: []string{"app/iam*"}}, []string{"net/vpc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			modules, err := tt.filter.Select(filterGraph())
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, modules)
+		})
+	}
+}
+
+func TestMockFilterSelect_Errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := terragrunt.Filter{IncludeDirs: []string{"app/iam3"}}.Select(filterGraph())
+	require.ErrorIs(t, err, terragrunt.ErrUnmatchedFilter)
+
+	// terragrunt only runs modules whose own dir matches, a parent dir selects nothing
+	_, err = terragrunt.Filter{IncludeDirs: []string{"app"}}.Select(filterGraph())
+	require.ErrorIs(t, err, terragrunt.ErrUnmatchedFilter)
+
+	_, err = terragrunt.Filter{ExcludeDirs: []string{"*/*"}}.Select(filterGraph())
+	require.ErrorIs(t, err, terragrunt.ErrEmptySelection)
+}
+
+func TestMockApplyWithFilter(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	mockExecutor.On("TgApplyAllE", t, mock.MatchedBy(func(options *terraform.Options) bool {
+		return options.EnvVars["TERRAGRUNT_INCLUDE_DIR"] == "app/iam" && options.EnvVars["TERRAGRUNT_STRICT_INCLUDE"] == "true"
+	})).Return("Mocked output", nil)
+
+	options := &terraform.Options{TerraformDir: "../../example", TerraformBinary: "terragrunt"}
+	filter := terragrunt.Filter{IncludeDirs: []string{"app/iam"}, StrictInclude: true}
+	err := terragrunt.ApplyWithFilter(t, options, mockExecutor, core.RunTime{}, cmdMockExecutor, filter)
+
+	require.NoError(t, err)
+	assert.Nil(t, options.EnvVars)
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestMockApplyWithFilter_PluginCacheInit(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	// Init only prepares the filtered modules
+	cmdMockExecutor.On("RunCommand", "terragrunt", mock.Anything, "../../example", mock.MatchedBy(func(envVars map[string]string) bool {
+		return envVars["TERRAGRUNT_INCLUDE_DIR"] == "app/iam" && envVars["TERRAGRUNT_NO_AUTO_INIT"] == "true"
+	})).Return([]byte("Terraform has been successfully initialized!"), nil)
+	mockExecutor.On("TgApplyAllE", t, mock.Anything).Return("Mocked output", nil)
+
+	options := &terraform.Options{TerraformDir: "../../example", TerraformBinary: "terragrunt"}
+	filter := terragrunt.Filter{IncludeDirs: []string{"app/iam"}}
+	err := terragrunt.ApplyWithFilter(t, options, mockExecutor, core.RunTime{IsPluginCache: true}, cmdMockExecutor, filter)
+
+	require.NoError(t, err)
+	cmdMockExecutor.AssertExpectations(t)
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestMockApplyWithFilter_Typo(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	options := &terraform.Options{TerraformDir: "../../example", TerraformBinary: "terragrunt"}
+	filter := terragrunt.Filter{IncludeDirs: []string{"app/iamm"}}
+	err := terragrunt.ApplyWithFilter(t, options, mockExecutor, core.RunTime{}, cmdMockExecutor, filter)
+
+	require.ErrorIs(t, err, terragrunt.ErrUnmatchedFilter)
+	mockExecutor.AssertNotCalled(t, "TgApplyAllE", t, mock.Anything)
+}
+
+func TestMockApplyWithFilter_UnparsedStack(t *testing.T) {
+	t.Parallel()
+	root := writeStack(t, "app/iam", "app/vpc")
+	// Discover cannot parse the vpc module, the globs are still matched against the stack
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app", "vpc", "terragrunt.hcl"), []byte("dependency \"iam\" {"), 0644))
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	mockExecutor.On("TgApplyAllE", t, mock.MatchedBy(func(options *terraform.Options) bool {
+		return options.EnvVars["TERRAGRUNT_INCLUDE_DIR"] == "app/*"
+	})).Return("Mocked output", nil).Twice()
+
+	options := &terraform.Options{TerraformDir: root, TerraformBinary: "terragrunt"}
+	for _, strict := range []bool{true, false} {
+		filter := terragrunt.Filter{IncludeDirs: []string{"app/*"}, StrictInclude: strict}
+		require.NoError(t, terragrunt.ApplyWithFilter(t, options, mockExecutor, core.RunTime{}, cmdMockExecutor, filter))
+	}
+
+	filter := terragrunt.Filter{ExcludeDirs: []string{"app/db"}}
+	err := terragrunt.ApplyWithFilter(t, options, mockExecutor, core.RunTime{}, cmdMockExecutor, filter)
+	require.ErrorIs(t, err, terragrunt.ErrUnmatchedFilter)
+	mockExecutor.AssertExpectations(t)
+}
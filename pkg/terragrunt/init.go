@@ -0,0 +1,158 @@
+package terragrunt
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// InitResult describes how terragrunt run-all init went.
+type InitResult struct {
+	// Attempts counts every init run, the first one included.
+	Attempts int
+	// Cleared lists the directories emptied before a re-init.
+	Cleared []string
+	// Problems holds the recognized problem of every failed attempt.
+	Problems []error
+	// Report is the analysis of the last attempt.
+	Report InitReport
+}
+
+// Init runs terragrunt run-all init with the plugin cache. After a recognized cache problem it clears
+// TgDownloadDir, and TfPluginDir with config.ClearPluginDir, then re-runs init up to config.InitRetries times.
+func Init(t *testing.T, options *terraform.Options, config core.RunTime, cmdExecutor CommandExecutor) (InitResult, error) {
+	return runInit(t, options, config, cmdExecutor, PhaseInit)
+}
+
+func runInit(t *testing.T, terra *terraform.Options, config core.RunTime, executor CommandExecutor, phase string) (InitResult, error) {
+	logger.Log(t, "TerraGrunt init in progress")
+
+	// Collect environment variables, those of terra such as the filter of ApplyWithFilter apply to init too.
+	envVars := terragruntEnv(config, true)
+	for key, value := range terra.EnvVars {
+		if _, ok := envVars[key]; !ok {
+			envVars[key] = value
+		}
+	}
+
+	// Command and arguments.
+	cmdName := "terragrunt"
+	args := []string{"run-all", "init", "--terragrunt-non-interactive", "--terragrunt-include-module-prefix"}
+
+	var result InitResult
+	backoff := config.InitBackoff
+	for {
+		result.Attempts++
+
+		// Run the command
+		output, streamed, err := runCommand(t, config, executor, phase, cmdName, args, terra.TerraformDir, envVars)
+		if config.IsDebug && !streamed {
+			logger.Log(t, "init output: %s\n", string(output))
+		}
+
+		var timeout *PhaseTimeoutError
+		if errors.As(err, &timeout) {
+
+			return result, fmt.Errorf("%w\nOutput:\n%s", err, output)
+		}
+
+		outcome, err := classifyInit(&result, output, err, config.InitRetries)
+		switch outcome {
+		case initDone:
+			logger.Log(t, "terragrunt init completed after", result.Attempts, "attempt(s),", result.Report.String())
+
+			return result, nil
+		case initFailed:
+
+			return result, failInit(t, config, err)
+		}
+
+		logger.Log(t, "terragrunt init attempt", result.Attempts, "failed:", err)
+		if outcome == initClearAndRetry {
+			if err := clearInitCache(t, config, &result); err != nil {
+
+				return result, err
+			}
+		}
+		logger.Log(t, "Retrying terragrunt init in", backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// initOutcome is what runInit does after an attempt.
+type initOutcome int
+
+const (
+	initDone initOutcome = iota
+	initRetry
+	initClearAndRetry
+	initFailed
+)
+
+// classifyInit analyzes the output of the last attempt into result and decides whether init is retried.
+// The returned error is the recognized problem of a retry or the final error of a failure.
+func classifyInit(result *InitResult, output []byte, err error, retries int) (initOutcome, error) {
+	result.Report = AnalyzeInitOutput(string(output))
+	problem := result.Report.Err()
+	if err == nil && problem == nil {
+
+		return initDone, nil
+	}
+	if problem != nil {
+		result.Problems = append(result.Problems, problem)
+	}
+
+	clearCache, retry := initRemedy(problem)
+	switch {
+	case retry && result.Attempts <= retries && clearCache:
+
+		return initClearAndRetry, problem
+	case retry && result.Attempts <= retries:
+
+		return initRetry, problem
+	case problem != nil:
+
+		return initFailed, fmt.Errorf("plugin cache out of order after %d attempt(s): %w\nOutput:\n%s", result.Attempts, problem, output)
+	default:
+
+		return initFailed, fmt.Errorf("%w\nOutput:\n%s", err, output)
+	}
+}
+
+// failInit clears the download dir and restores the vars file after init gave up.
+func failInit(t *testing.T, config core.RunTime, err error) error {
+	errs := []error{err}
+	if err := core.ClearFolder(t, config, core.OsFileSystem{}); err != nil {
+		errs = append(errs, fmt.Errorf("clearing chache folder failed: %w", err))
+	}
+	if err := core.RestoreVarsFile(t, config, core.OsFileSystem{}); err != nil {
+		errs = append(errs, fmt.Errorf("restore vars file failed: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// clearInitCache empties the download dir and, if configured, the plugin dir and records them in result.
+func clearInitCache(t *testing.T, config core.RunTime, result *InitResult) error {
+	if err := core.ClearFolder(t, config, core.OsFileSystem{}); err != nil {
+
+		return fmt.Errorf("clearing chache folder failed: %w", err)
+	}
+	result.Cleared = append(result.Cleared, config.Paths.TgDownloadDir)
+
+	if config.ClearPluginDir {
+		if err := core.ClearPluginFolder(t, config, core.OsFileSystem{}); err != nil {
+
+			return fmt.Errorf("clearing plugin folder failed: %w", err)
+		}
+		result.Cleared = append(result.Cleared, config.Paths.TfPluginDir)
+	}
+
+	return nil
+}
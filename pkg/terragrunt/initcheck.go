@@ -0,0 +1,98 @@
+package terragrunt
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	cacheHitRegex  = regexp.MustCompile(`- Using (\S+) v(\S+) from the shared cache directory`)
+	reusedRegex    = regexp.MustCompile(`- Using previously-installed (\S+) v(\S+)`)
+	installedRegex = regexp.MustCompile(`- Installed (\S+) v(\S+)`)
+	boxRegex       = regexp.MustCompile(`[│╷╵]`)
+	spaceRegex     = regexp.MustCompile(`\s+`)
+)
+
+// initProblems maps the messages terraform prints to the error they stand for.
+var initProblems = []struct {
+	messages []string
+	err      error
+}{
+	{[]string{"doesn't match any of the checksums", "does not match any of the checksums", "checksum list has no SHA-256 hash"}, ErrProviderChecksum},
+	{[]string{"Inconsistent dependency lock file"}, ErrLockFileInconsistent},
+	{[]string{"text file busy"}, ErrTextFileBusy},
+}
+
+// InitReport is what terraform init printed about the providers and the problems it ran into.
+type InitReport struct {
+	// CacheHits are providers linked from the shared plugin cache.
+	CacheHits []string
+	// Reused are providers already installed in the module.
+	Reused []string
+	// Installed are providers downloaded from the registry.
+	Installed []string
+	Problems  []error
+}
+
+// AnalyzeInitOutput parses the output of terragrunt run-all init.
+func AnalyzeInitOutput(output string) InitReport {
+	var report InitReport
+
+	output = ansiRegex.ReplaceAllString(output, "")
+	for _, match := range cacheHitRegex.FindAllStringSubmatch(output, -1) {
+		report.CacheHits = append(report.CacheHits, match[1]+" "+match[2])
+	}
+	for _, match := range reusedRegex.FindAllStringSubmatch(output, -1) {
+		report.Reused = append(report.Reused, match[1]+" "+match[2])
+	}
+	for _, match := range installedRegex.FindAllStringSubmatch(output, -1) {
+		report.Installed = append(report.Installed, match[1]+" "+match[2])
+	}
+
+	// Diagnostics are wrapped inside a box, flatten them so messages split over lines still match.
+	flat := spaceRegex.ReplaceAllString(boxRegex.ReplaceAllString(output, " "), " ")
+	for _, problem := range initProblems {
+		for _, message := range problem.messages {
+			if strings.Contains(flat, message) {
+				report.Problems = append(report.Problems, problem.err)
+
+				break
+			}
+		}
+	}
+
+	return report
+}
+
+// Err joins the problems found, nil if init looked healthy.
+func (r InitReport) Err() error {
+	return errors.Join(r.Problems...)
+}
+
+// String summarizes where the providers came from.
+func (r InitReport) String() string {
+	return fmt.Sprintf("%d providers from the plugin cache, %d reused, %d downloaded",
+		len(r.CacheHits), len(r.Reused), len(r.Installed))
+}
+
+// initRemedy tells how tGiNit recovers from a problem before the single re-init.
+func initRemedy(err error) (clearCache bool, retry bool) {
+	switch {
+	case errors.Is(err, ErrProviderChecksum), errors.Is(err, ErrLockFileInconsistent):
+		// Stale copies in the download dir carry the lock files and providers that disagree.
+		return true, true
+	case errors.Is(err, ErrTextFileBusy):
+		// Another init was writing the same provider into the cache, it is done by now.
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+var (
+	ErrProviderChecksum     = errors.New("provider checksum does not match the dependency lock file")
+	ErrLockFileInconsistent = errors.New("dependency lock file is inconsistent with the configuration")
+	ErrTextFileBusy         = errors.New("provider binary in the plugin cache is busy")
+)
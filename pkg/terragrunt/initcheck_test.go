@@ -0,0 +1,184 @@
+package terragrunt_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/terragrunt"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func readInitOutput(t *testing.T, name string) []byte {
+	t.Helper()
+	output, err := os.ReadFile(filepath.Join("testdata", "init", name))
+	require.NoError(t, err)
+
+	return output
+}
+
+func TestMockAnalyzeInitOutput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		file      string
+		cacheHits []string
+		reused    []string
+		installed []string
+		problem   error
+	}{
+		{file: "cache_hit.txt", cacheHits: []string{"hashicorp/aws 5.31.0"}},
+		{file: "previously_installed.txt", reused: []string{"hashicorp/aws 5.31.0"}},
+		{file: "installed.txt", installed: []string{"hashicorp/aws 5.31.0"}},
+		{file: "checksum_mismatch.txt", problem: terragrunt.ErrProviderChecksum},
+		{file: "lock_inconsistent.txt", problem: terragrunt.ErrLockFileInconsistent},
+		{file: "text_file_busy.txt", problem: terragrunt.ErrTextFileBusy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			t.Parallel()
+
+			report := terragrunt.AnalyzeInitOutput(string(readInitOutput(t, tt.file)))
+
+			assert.Equal(t, tt.cacheHits, report.CacheHits)
+			assert.Equal(t, tt.reused, report.Reused)
+			assert.Equal(t, tt.installed, report.Installed)
+			if tt.problem == nil {
+				require.NoError(t, report.Err())
+
+				return
+			}
+			require.ErrorIs(t, report.Err(), tt.problem)
+			assert.Len(t, report.Problems, 1)
+		})
+	}
+}
+
+// initConfig returns a plugin cache config with a captured vars file so a failed init can restore it.
+func initConfig(t *testing.T) core.RunTime {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "root_vars.hcl"), []byte("locals {}\n"), 0644))
+	config := core.RunTime{
+		Paths:         core.FolderPaths{TerragruntDir: dir, TgDownloadDir: t.TempDir()},
+		VarsFile:      "root_vars.hcl",
+		Content:       "locals {}\n",
+		IsPluginCache: true,
+		InitRetries:   1,
+		InitBackoff:   time.Millisecond,
+	}
+	_, err := core.UpdateVarsFile(t, config, core.OsFileSystem{})
+	require.NoError(t, err)
+
+	return config
+}
+
+func TestMockTgInit_RetriesBusyCache(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	cmdMockExecutor.On("RunCommand", "terragrunt", mock.Anything, mock.Anything, mock.Anything).
+		Return(readInitOutput(t, "text_file_busy.txt"), fmt.Errorf("exit status 1")).Once()
+	cmdMockExecutor.On("RunCommand", "terragrunt", mock.Anything, mock.Anything, mock.Anything).
+		Return(readInitOutput(t, "cache_hit.txt"), nil).Once()
+	mockExecutor.On("TgApplyAllE", t, mock.AnythingOfType("*terraform.Options")).Return("Mocked output", nil)
+
+	err := terragrunt.Apply(t, &terraform.Options{}, mockExecutor, initConfig(t), cmdMockExecutor)
+
+	require.NoError(t, err)
+	cmdMockExecutor.AssertNumberOfCalls(t, "RunCommand", 2)
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestMockTgInit_ChecksumMismatch(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	config := initConfig(t)
+	stale := filepath.Join(config.Paths.TgDownloadDir, "stale")
+	require.NoError(t, os.Mkdir(stale, 0755))
+
+	cmdMockExecutor.On("RunCommand", "terragrunt", mock.Anything, mock.Anything, mock.Anything).
+		Return(readInitOutput(t, "checksum_mismatch.txt"), fmt.Errorf("exit status 1"))
+
+	err := terragrunt.Apply(t, &terraform.Options{}, mockExecutor, config, cmdMockExecutor)
+
+	require.ErrorIs(t, err, terragrunt.ErrProviderChecksum)
+	// The download dir is cleared before the single re-init.
+	cmdMockExecutor.AssertNumberOfCalls(t, "RunCommand", 2)
+	assert.NoDirExists(t, stale)
+	mockExecutor.AssertNotCalled(t, "TgApplyAllE", t, mock.Anything)
+}
+
+func TestMockTgInit_UnknownFailure(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	cmdMockExecutor.On("RunCommand", "terragrunt", mock.Anything, mock.Anything, mock.Anything).
+		Return([]byte("Error: Invalid block definition"), errors.New("exit status 1"))
+
+	err := terragrunt.Apply(t, &terraform.Options{}, mockExecutor, initConfig(t), cmdMockExecutor)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid block definition")
+	cmdMockExecutor.AssertNumberOfCalls(t, "RunCommand", 1)
+}
+
+func TestMockInit_SelfHealing(t *testing.T) {
+	t.Parallel()
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	config := initConfig(t)
+	config.InitRetries = 3
+	config.ClearPluginDir = true
+	config.Paths.TfPluginDir = t.TempDir()
+	provider := filepath.Join(config.Paths.TfPluginDir, "registry.terraform.io")
+	require.NoError(t, os.Mkdir(provider, 0755))
+
+	cmdMockExecutor.On("RunCommand", "terragrunt", mock.Anything, mock.Anything, mock.Anything).
+		Return(readInitOutput(t, "lock_inconsistent.txt"), fmt.Errorf("exit status 1")).Twice()
+	cmdMockExecutor.On("RunCommand", "terragrunt", mock.Anything, mock.Anything, mock.Anything).
+		Return(readInitOutput(t, "installed.txt"), nil).Once()
+
+	result, err := terragrunt.Init(t, &terraform.Options{}, config, cmdMockExecutor)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Attempts)
+	assert.Len(t, result.Problems, 2)
+	require.ErrorIs(t, result.Problems[0], terragrunt.ErrLockFileInconsistent)
+	assert.Equal(t, []string{
+		config.Paths.TgDownloadDir, config.Paths.TfPluginDir,
+		config.Paths.TgDownloadDir, config.Paths.TfPluginDir,
+	}, result.Cleared)
+	assert.Equal(t, []string{"hashicorp/aws 5.31.0"}, result.Report.Installed)
+	assert.NoDirExists(t, provider)
+}
+
+func TestMockInit_RetriesExhausted(t *testing.T) {
+	t.Parallel()
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	config := initConfig(t)
+	config.InitRetries = 2
+
+	cmdMockExecutor.On("RunCommand", "terragrunt", mock.Anything, mock.Anything, mock.Anything).
+		Return(readInitOutput(t, "text_file_busy.txt"), fmt.Errorf("exit status 1"))
+
+	result, err := terragrunt.Init(t, &terraform.Options{}, config, cmdMockExecutor)
+
+	require.ErrorIs(t, err, terragrunt.ErrTextFileBusy)
+	assert.Contains(t, err.Error(), "after 3 attempt(s)")
+	assert.Equal(t, 3, result.Attempts)
+	assert.Empty(t, result.Cleared)
+}
@@ -0,0 +1,193 @@
+package terragrunt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/stack"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// Outputs holds terraform outputs keyed by module path, relative to the stack dir, and output name.
+type Outputs map[string]map[string]any
+
+// StackOutputs runs terragrunt output -json in every module of the stack under options.TerraformDir,
+// as found by stack.FindModules, so configs are not evaluated. A module dir without nested modules is
+// read as RootModule.
+func StackOutputs(t *testing.T, options *terraform.Options, config core.RunTime, cmdExecutor CommandExecutor) (Outputs, error) {
+	modules, err := stack.FindModules(options.TerraformDir)
+	if err != nil {
+
+		return nil, fmt.Errorf("failed to discover modules: %w", err)
+	}
+
+	envVars := terragruntEnv(config, config.IsPluginCache)
+	for key, value := range options.EnvVars {
+		envVars[key] = value
+	}
+	// Debug logs would end up in the parsed output of executors that cannot separate stderr.
+	for key := range debugEnv {
+		delete(envVars, key)
+	}
+
+	root, err := filepath.Abs(options.TerraformDir)
+	if err != nil {
+
+		return nil, err
+	}
+
+	outputs := Outputs{}
+	for _, module := range modules {
+		logger.Log(t, "Reading outputs of", module)
+		output, _, err := runCommand(t, config, cmdExecutor, PhaseOutput, "terragrunt",
+			[]string{"output", "-json", "--terragrunt-non-interactive"}, filepath.Join(root, filepath.FromSlash(module)), envVars)
+		if err != nil {
+
+			return nil, fmt.Errorf("failed to read outputs of %s: %w\nOutput:\n%s", module, err, output)
+		}
+
+		values, err := parseOutputJSON(output)
+		if err != nil {
+
+			return nil, fmt.Errorf("failed to parse outputs of %s: %w", module, err)
+		}
+		outputs[module] = values
+	}
+
+	return outputs, nil
+}
+
+// parseOutputJSON decodes the object printed by terraform output -json. Lines before it are skipped,
+// for executors returning stdout and stderr together, anything after it is ignored.
+func parseOutputJSON(output []byte) (map[string]any, error) {
+	start := 0
+	if !bytes.HasPrefix(output, []byte("{")) {
+		start = bytes.Index(output, []byte("\n{")) + 1
+		if start == 0 {
+
+			return map[string]any{}, nil
+		}
+	}
+
+	var raw map[string]struct {
+		Value any `json:"value"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(output[start:])).Decode(&raw); err != nil {
+
+		return nil, err
+	}
+
+	values := make(map[string]any, len(raw))
+	for name, output := range raw {
+		values[name] = output.Value
+	}
+
+	return values, nil
+}
+
+// Get returns the raw value of an output.
+func (o Outputs) Get(module, name string) (any, error) {
+	values, ok := o[module]
+	if !ok {
+
+		return nil, fmt.Errorf("%w: module %s", ErrOutputNotFound, module)
+	}
+	value, ok := values[name]
+	if !ok {
+
+		return nil, fmt.Errorf("%w: %s in module %s", ErrOutputNotFound, name, module)
+	}
+
+	return value, nil
+}
+
+// String returns a string output.
+func (o Outputs) String(module, name string) (string, error) {
+	return outputAs[string](o, module, name)
+}
+
+// Bool returns a bool output.
+func (o Outputs) Bool(module, name string) (bool, error) {
+	return outputAs[bool](o, module, name)
+}
+
+// Number returns a number output, JSON numbers are decoded as float64.
+func (o Outputs) Number(module, name string) (float64, error) {
+	return outputAs[float64](o, module, name)
+}
+
+// Map returns an object or map output.
+func (o Outputs) Map(module, name string) (map[string]any, error) {
+	return outputAs[map[string]any](o, module, name)
+}
+
+// List returns a list, set or tuple output.
+func (o Outputs) List(module, name string) ([]any, error) {
+	return outputAs[[]any](o, module, name)
+}
+
+// StringList returns a list output whose elements are all strings.
+func (o Outputs) StringList(module, name string) ([]string, error) {
+	list, err := o.List(module, name)
+	if err != nil {
+
+		return nil, err
+	}
+
+	result := make([]string, 0, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok {
+
+			return nil, fmt.Errorf("%w: %s[%d] in module %s is %T", ErrOutputType, name, i, module, item)
+		}
+		result = append(result, s)
+	}
+
+	return result, nil
+}
+
+// Decode unmarshals an output into target, for outputs with a known structure.
+func (o Outputs) Decode(module, name string, target any) error {
+	value, err := o.Get(module, name)
+	if err != nil {
+
+		return err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+
+		return err
+	}
+
+	return json.Unmarshal(data, target)
+}
+
+func outputAs[T any](o Outputs, module, name string) (T, error) {
+	var zero T
+
+	value, err := o.Get(module, name)
+	if err != nil {
+
+		return zero, err
+	}
+	typed, ok := value.(T)
+	if !ok {
+
+		return zero, fmt.Errorf("%w: %s in module %s is %T, not %T", ErrOutputType, name, module, value, zero)
+	}
+
+	return typed, nil
+}
+
+var (
+	ErrOutputNotFound = errors.New("output not found")
+	ErrOutputType     = errors.New("output has a different type")
+)
@@ -0,0 +1,143 @@
+package terragrunt_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/terragrunt"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func writeStack(t *testing.T, modules ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, module := range append(modules, ".", ".terragrunt-cache/abc") {
+		dir := filepath.Join(root, module)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		// Modules include the root config, which makes it no module of its own.
+		config := "include \"root\" {\n  path = find_in_parent_folders()\n}\n"
+		if module == "." {
+			config = ""
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "terragrunt.hcl"), []byte(config), 0644))
+	}
+
+	return root
+}
+
+func TestMockStackOutputs_SingleModule(t *testing.T) {
+	t.Parallel()
+	root := writeStack(t, "app/iam")
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	cmdMockExecutor.On("RunCommand", "terragrunt", mock.Anything, filepath.Join(root, "app/iam"), mock.Anything).
+		Return([]byte(`{"count": {"sensitive": false, "type": "number", "value": 1}}`), nil)
+
+	outputs, err := terragrunt.StackOutputs(t, &terraform.Options{TerraformDir: filepath.Join(root, "app", "iam")}, core.RunTime{}, cmdMockExecutor)
+	require.NoError(t, err)
+
+	count, err := outputs.Number(terragrunt.RootModule, "count")
+	require.NoError(t, err)
+	assert.InDelta(t, 1, count, 0)
+	cmdMockExecutor.AssertExpectations(t)
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestMockStackOutputs_StdoutOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake terragrunt is a shell script")
+	}
+	root := writeStack(t, "app/iam")
+
+	// The fake terragrunt logs braces to stderr and prints the outputs to stdout.
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho 'level=debug msg={\"inputs\": {}}' >&2\n" +
+		"echo '{\"name\": {\"sensitive\": false, \"type\": \"string\", \"value\": \"iam\"}}'\n" +
+		"echo 'level=debug msg=done}' >&2\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "terragrunt"), []byte(script), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	outputs, err := terragrunt.StackOutputs(t, &terraform.Options{TerraformDir: root}, core.RunTime{IsDebug: true}, &terragrunt.RealCommandExecutor{})
+	require.NoError(t, err)
+
+	name, err := outputs.String("app/iam", "name")
+	require.NoError(t, err)
+	assert.Equal(t, "iam", name)
+}
+
+func TestMockStackOutputs(t *testing.T) {
+	t.Parallel()
+	root := writeStack(t, "app/iam", "app/iam2")
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	args := []string{"output", "-json", "--terragrunt-non-interactive"}
+	cmdMockExecutor.On("RunCommand", "terragrunt", args, filepath.Join(root, "app/iam"), mock.Anything).
+		Return([]byte(`time=now level=info msg=reading
+{"policy_arn": {"sensitive": false, "type": "string", "value": "arn:aws:iam::123:policy/a"},
+ "names": {"sensitive": false, "type": ["list", "string"], "value": ["a", "b"]}}`), nil)
+	cmdMockExecutor.On("RunCommand", "terragrunt", args, filepath.Join(root, "app/iam2"), mock.Anything).
+		Return([]byte(`{"count": {"sensitive": false, "type": "number", "value": 2}}`), nil)
+
+	outputs, err := terragrunt.StackOutputs(t, &terraform.Options{TerraformDir: root}, core.RunTime{}, cmdMockExecutor)
+	require.NoError(t, err)
+
+	arn, err := outputs.String("app/iam", "policy_arn")
+	require.NoError(t, err)
+	assert.Equal(t, "arn:aws:iam::123:policy/a", arn)
+
+	names, err := outputs.StringList("app/iam", "names")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+
+	count, err := outputs.Number("app/iam2", "count")
+	require.NoError(t, err)
+	assert.InDelta(t, 2, count, 0)
+
+	_, err = outputs.String("app/iam2", "count")
+	require.ErrorIs(t, err, terragrunt.ErrOutputType)
+
+	_, err = outputs.String("app/iam2", "missing")
+	require.ErrorIs(t, err, terragrunt.ErrOutputNotFound)
+	cmdMockExecutor.AssertExpectations(t)
+}
+
+func TestMockStackOutputs_Failure(t *testing.T) {
+	t.Parallel()
+	root := writeStack(t, "app/iam")
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	cmdMockExecutor.On("RunCommand", "terragrunt", mock.Anything, mock.Anything, mock.Anything).
+		Return([]byte("no state"), fmt.Errorf("Mocked error"))
+
+	_, err := terragrunt.StackOutputs(t, &terraform.Options{TerraformDir: root}, core.RunTime{}, cmdMockExecutor)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read outputs of app/iam")
+}
+
+func TestMockStackOutputs_UnevaluatedConfig(t *testing.T) {
+	t.Parallel()
+	root := writeStack(t, "app/iam")
+	// Outputs are read without evaluating the configs, functions the stack package does not know are fine.
+	config := "dependency \"vpc\" {\n  config_path = run_cmd(\"echo\", \"../vpc\")\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app", "iam", "terragrunt.hcl"), []byte(config), 0644))
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	cmdMockExecutor.On("RunCommand", "terragrunt", mock.Anything, filepath.Join(root, "app/iam"), mock.Anything).
+		Return([]byte(`{"name": {"sensitive": false, "type": "string", "value": "iam"}}`), nil)
+
+	outputs, err := terragrunt.StackOutputs(t, &terraform.Options{TerraformDir: root}, core.RunTime{}, cmdMockExecutor)
+	require.NoError(t, err)
+
+	name, err := outputs.String("app/iam", "name")
+	require.NoError(t, err)
+	assert.Equal(t, "iam", name)
+	cmdMockExecutor.AssertExpectations(t)
+}
@@ -0,0 +1,248 @@
+package terragrunt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// RootModule is the key used for plan output that carries no module prefix.
+const RootModule = "."
+
+var (
+	ansiRegex       = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	modulePrefix    = regexp.MustCompile(`^\[([^\]]+)\]\s?(.*)$`)
+	planChangeRegex = regexp.MustCompile(`Plan: (?:(\d+) to import, )?(\d+) to add, (\d+) to change, (\d+) to destroy\.`)
+	resourceRegex   = regexp.MustCompile(`^\s*# (\S+) (?:will be|must be) (.+)$`)
+)
+
+// ResourceChange is a resource listed in the plan with the action terraform will take.
+type ResourceChange struct {
+	Address string
+	Action  string
+}
+
+// ModulePlan is the plan summary of a single terragrunt module.
+type ModulePlan struct {
+	Add       int
+	Change    int
+	Destroy   int
+	Import    int
+	NoChanges bool
+	Resources []ResourceChange
+}
+
+// HasChanges reports whether the module plans any change.
+func (m ModulePlan) HasChanges() bool {
+	return m.Add+m.Change+m.Destroy+m.Import > 0
+}
+
+// PlanSummary is the result of run-all plan keyed by module path.
+type PlanSummary struct {
+	ExitCode int
+	Modules  map[string]ModulePlan
+}
+
+// HasChanges reports whether any module plans a change.
+func (p PlanSummary) HasChanges() bool {
+	for _, module := range p.Modules {
+		if module.HasChanges() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TotalDestroy returns the number of resources planned for destruction across all modules.
+func (p PlanSummary) TotalDestroy() int {
+	total := 0
+	for _, module := range p.Modules {
+		total += module.Destroy
+	}
+
+	return total
+}
+
+// ModuleNames returns the planned modules sorted by path.
+func (p PlanSummary) ModuleNames() []string {
+	names := make([]string, 0, len(p.Modules))
+	for name := range p.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ParsePlanOutput builds a per-module summary from run-all plan output.
+// Lines prefixed with [module] are attributed to that module, the rest to RootModule.
+func ParsePlanOutput(output string) map[string]ModulePlan {
+	modules := map[string]ModulePlan{}
+
+	scanner := bufio.NewScanner(strings.NewReader(ansiRegex.ReplaceAllString(output, "")))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		module, line := splitModulePrefix(scanner.Text())
+
+		if strings.Contains(line, "No changes.") {
+			plan := modules[module]
+			plan.NoChanges = true
+			modules[module] = plan
+
+			continue
+		}
+
+		if match := resourceRegex.FindStringSubmatch(line); match != nil {
+			plan := modules[module]
+			plan.Resources = append(plan.Resources, ResourceChange{Address: match[1], Action: match[2]})
+			modules[module] = plan
+
+			continue
+		}
+
+		match := planChangeRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		plan := modules[module]
+		plan.Import = atoi(match[1])
+		plan.Add = atoi(match[2])
+		plan.Change = atoi(match[3])
+		plan.Destroy = atoi(match[4])
+		modules[module] = plan
+	}
+
+	return modules
+}
+
+func splitModulePrefix(line string) (string, string) {
+	match := modulePrefix.FindStringSubmatch(line)
+	if match == nil {
+		return RootModule, line
+	}
+
+	return match[1], match[2]
+}
+
+func atoi(value string) int {
+	n, _ := strconv.Atoi(value)
+
+	return n
+}
+
+// Plan runs terragrunt run-all plan and returns a per-module summary of the planned changes.
+func Plan(t *testing.T, options *terraform.Options, executor Executor, config core.RunTime, cmdExecutor CommandExecutor) (PlanSummary, error) {
+	unlock, err := lockCache(t, config)
+	if err != nil {
+
+		return PlanSummary{}, err
+	}
+	defer unlock()
+	// The vars file is not restored ahead of the deadline while terragrunt reads it.
+	defer core.UseVarsFile(config)()
+
+	setRunEnv(options, config)
+
+	if config.IsPluginCache {
+		if err := tGiNit(t, options, config, cmdExecutor, PhaseInit); err != nil {
+
+			return PlanSummary{}, fmt.Errorf("terragrunt init failed: %w", err)
+		}
+	}
+
+	setDebugEnv(config)
+
+	logger.Log(t, "TerraGrunt plan in progress")
+	output, exitCode, err := planAll(t, options, executor, config, cmdExecutor)
+	if err != nil {
+
+		return PlanSummary{ExitCode: exitCode}, fmt.Errorf("failed to plan Terragrunt ,output: %s, error: %w", output, err)
+	}
+
+	summary := PlanSummary{
+		ExitCode: exitCode,
+		Modules:  ParsePlanOutput(output),
+	}
+	for _, name := range summary.ModuleNames() {
+		module := summary.Modules[name]
+		logger.Log(t, fmt.Sprintf("Plan %s: %d to import, %d to add, %d to change, %d to destroy",
+			name, module.Import, module.Add, module.Change, module.Destroy))
+	}
+
+	return summary, nil
+}
+
+// planAll runs the plan with the deadline of PhasePlan when executor can be cancelled.
+func planAll(t *testing.T, options *terraform.Options, executor Executor, config core.RunTime, cmdExecutor CommandExecutor) (string, int, error) {
+	ctxExecutor, ok := executor.(ContextExecutor)
+	if !ok {
+		return executor.TgPlanAllE(t, options)
+	}
+
+	exitCode := terraform.DefaultErrorExitCode
+	output, err := runPhase(t, config, PhasePlan, options.TerraformDir, cmdExecutor, func(ctx context.Context) (string, error) {
+		var output string
+		var err error
+		output, exitCode, err = ctxExecutor.TgPlanAllContext(ctx, t, options)
+
+		return output, err
+	})
+
+	return output, exitCode, err
+}
+
+// ApplyIdempotent applies the stack and then plans it again, it fails if the second plan still shows changes.
+func ApplyIdempotent(t *testing.T, options *terraform.Options, executor Executor, config core.RunTime, cmdExecutor CommandExecutor) error {
+	if err := Apply(t, options, executor, config, cmdExecutor); err != nil {
+
+		return err
+	}
+
+	logger.Log(t, "Checking the stack converged")
+	summary, err := Plan(t, options, executor, config, cmdExecutor)
+	if err != nil {
+
+		return err
+	}
+
+	if summary.ExitCode == terraform.DefaultSuccessExitCode && !summary.HasChanges() {
+
+		return nil
+	}
+
+	return fmt.Errorf("%w:\n%s", ErrNotIdempotent, summary.Diff())
+}
+
+// Diff lists every module with pending changes and its resources.
+func (p PlanSummary) Diff() string {
+	var b strings.Builder
+	for _, name := range p.ModuleNames() {
+		module := p.Modules[name]
+		if !module.HasChanges() && len(module.Resources) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: %d to import, %d to add, %d to change, %d to destroy\n",
+			name, module.Import, module.Add, module.Change, module.Destroy)
+		for _, resource := range module.Resources {
+			fmt.Fprintf(&b, "  %s %s\n", resource.Address, resource.Action)
+		}
+	}
+	if b.Len() == 0 {
+		fmt.Fprintf(&b, "plan exited with code %d\n", p.ExitCode)
+	}
+
+	return b.String()
+}
+
+var ErrNotIdempotent = errors.New("stack did not converge, second plan shows changes")
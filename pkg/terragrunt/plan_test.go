@@ -0,0 +1,159 @@
+package terragrunt_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/terragrunt"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const planOutput = "[app/iam] \x1b[1mPlan:\x1b[0m 1 to add, 2 to change, 0 to destroy.\n" +
+	"[app/vpc] No changes. Your infrastructure matches the configuration.\n" +
+	"[app/s3] Plan: 1 to import, 0 to add, 0 to change, 3 to destroy.\n"
+
+func TestMockParsePlanOutput(t *testing.T) {
+	t.Parallel()
+
+	modules := terragrunt.ParsePlanOutput(planOutput)
+
+	assert.Equal(t, terragrunt.ModulePlan{Add: 1, Change: 2}, modules["app/iam"])
+	assert.Equal(t, terragrunt.ModulePlan{NoChanges: true}, modules["app/vpc"])
+	assert.Equal(t, terragrunt.ModulePlan{Import: 1, Destroy: 3}, modules["app/s3"])
+}
+
+func TestMockParsePlanOutput_NoPrefix(t *testing.T) {
+	t.Parallel()
+
+	modules := terragrunt.ParsePlanOutput("Plan: 4 to add, 0 to change, 0 to destroy.\n")
+
+	assert.Equal(t, terragrunt.ModulePlan{Add: 4}, modules[terragrunt.RootModule])
+}
+
+func TestMockTgPlan_Changes(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	mockExecutor.On("TgPlanAllE", t, mock.AnythingOfType("*terraform.Options")).
+		Return(planOutput, terraform.TerraformPlanChangesPresentExitCode, nil)
+
+	summary, err := terragrunt.Plan(t, &terraform.Options{}, mockExecutor, core.RunTime{}, cmdMockExecutor)
+
+	require.NoError(t, err)
+	assert.Equal(t, terraform.TerraformPlanChangesPresentExitCode, summary.ExitCode)
+	assert.True(t, summary.HasChanges())
+	assert.Equal(t, 3, summary.TotalDestroy())
+	assert.Equal(t, []string{"app/iam", "app/s3", "app/vpc"}, summary.ModuleNames())
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestMockTgPlan_NoChanges(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	mockExecutor.On("TgPlanAllE", t, mock.AnythingOfType("*terraform.Options")).
+		Return("[app/vpc] No changes.\n", terraform.DefaultSuccessExitCode, nil)
+
+	summary, err := terragrunt.Plan(t, &terraform.Options{}, mockExecutor, core.RunTime{}, cmdMockExecutor)
+
+	require.NoError(t, err)
+	assert.False(t, summary.HasChanges())
+	assert.Zero(t, summary.TotalDestroy())
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestMockTgPlan_Failure(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	mockExecutor.On("TgPlanAllE", t, mock.AnythingOfType("*terraform.Options")).
+		Return("", terraform.DefaultErrorExitCode, fmt.Errorf("Mocked error"))
+
+	summary, err := terragrunt.Plan(t, &terraform.Options{}, mockExecutor, core.RunTime{}, cmdMockExecutor)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to plan Terragrunt")
+	assert.Equal(t, terraform.DefaultErrorExitCode, summary.ExitCode)
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestMockTgPlanAllE_InvalidBinary(t *testing.T) {
+	t.Parallel()
+	executor := &terragrunt.RealTerragruntExecutor{}
+
+	_, exitCode, err := executor.TgPlanAllE(t, &terraform.Options{TerraformBinary: "terraform"})
+
+	require.Error(t, err)
+	assert.Equal(t, terraform.DefaultErrorExitCode, exitCode)
+}
+
+func TestMockParsePlanOutput_Resources(t *testing.T) {
+	t.Parallel()
+
+	output := "[app/iam]   # aws_iam_policy.this will be updated in-place\n" +
+		"[app/iam]   # aws_iam_role.this must be replaced\n" +
+		"[app/iam] Plan: 1 to add, 1 to change, 1 to destroy.\n"
+
+	modules := terragrunt.ParsePlanOutput(output)
+
+	assert.Equal(t, []terragrunt.ResourceChange{
+		{Address: "aws_iam_policy.this", Action: "updated in-place"},
+		{Address: "aws_iam_role.this", Action: "replaced"},
+	}, modules["app/iam"].Resources)
+	assert.Equal(t, 1, modules["app/iam"].Destroy)
+}
+
+func TestMockApplyIdempotent_Converged(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	mockExecutor.On("TgApplyAllE", t, mock.AnythingOfType("*terraform.Options")).Return("Mocked output", nil)
+	mockExecutor.On("TgPlanAllE", t, mock.AnythingOfType("*terraform.Options")).
+		Return("[app/iam] No changes.\n", terraform.DefaultSuccessExitCode, nil)
+
+	err := terragrunt.ApplyIdempotent(t, &terraform.Options{}, mockExecutor, core.RunTime{}, cmdMockExecutor)
+
+	require.NoError(t, err)
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestMockApplyIdempotent_Drift(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	mockExecutor.On("TgApplyAllE", t, mock.AnythingOfType("*terraform.Options")).Return("Mocked output", nil)
+	mockExecutor.On("TgPlanAllE", t, mock.AnythingOfType("*terraform.Options")).
+		Return("[app/iam]   # aws_iam_policy.this will be updated in-place\n"+
+			"[app/iam] Plan: 0 to add, 1 to change, 0 to destroy.\n"+
+			"[app/iam2] No changes.\n", terraform.TerraformPlanChangesPresentExitCode, nil)
+
+	err := terragrunt.ApplyIdempotent(t, &terraform.Options{}, mockExecutor, core.RunTime{}, cmdMockExecutor)
+
+	require.ErrorIs(t, err, terragrunt.ErrNotIdempotent)
+	assert.Contains(t, err.Error(), "app/iam: 0 to import, 0 to add, 1 to change, 0 to destroy")
+	assert.Contains(t, err.Error(), "aws_iam_policy.this updated in-place")
+	assert.NotContains(t, err.Error(), "app/iam2")
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestMockApplyIdempotent_ApplyFailure(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	mockExecutor.On("TgApplyAllE", t, mock.AnythingOfType("*terraform.Options")).Return("", fmt.Errorf("Mocked error"))
+
+	err := terragrunt.ApplyIdempotent(t, &terraform.Options{}, mockExecutor, core.RunTime{}, cmdMockExecutor)
+
+	require.Error(t, err)
+	mockExecutor.AssertNotCalled(t, "TgPlanAllE", t, mock.Anything)
+}
@@ -0,0 +1,101 @@
+package terragrunt
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// AWSEventualConsistencyErrors are AWS errors that go away once a change has propagated.
+var AWSEventualConsistencyErrors = map[string]string{
+	"(?s).*DependencyViolation.*":                  "resource still has a dependent object, such as an ENI that is being released",
+	"(?s).*InvalidNetworkInterfaceID\\.NotFound.*": "network interface not visible yet",
+	"(?s).*InvalidGroup\\.NotFound.*":              "security group not visible yet",
+	"(?s).*NoSuchEntity: The (?:role|user|group|instance profile) with name \\S+ cannot be found.*": "IAM entity not propagated yet",
+	"(?s).*MalformedPolicyDocument: Invalid principal in policy.*":                                  "IAM principal not propagated yet",
+	"(?s).*Value .* for parameter iamInstanceProfile\\.name is invalid.*":                           "IAM instance profile not propagated yet",
+	"(?s).*The role defined for the function cannot be assumed by Lambda.*":                         "IAM role not propagated to Lambda yet",
+}
+
+type retryableError struct {
+	regex  *regexp.Regexp
+	reason string
+}
+
+// retryableErrors merges terratest's defaults, the AWS errors and policy in that order, later ones win.
+// Patterns are sorted so the reason logged for an output is always the same.
+func retryableErrors(policy core.RetryPolicy) []retryableError {
+	merged := map[string]string{}
+	for _, source := range []map[string]string{terraform.DefaultRetryableTerraformErrors, AWSEventualConsistencyErrors, policy.RetryableErrors} {
+		for pattern, reason := range source {
+			merged[pattern] = reason
+		}
+	}
+
+	patterns := make([]string, 0, len(merged))
+	for pattern := range merged {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	matchers := make([]retryableError, 0, len(patterns))
+	for _, pattern := range patterns {
+		// Invalid patterns are reported by core.LoadConfig.
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		matchers = append(matchers, retryableError{regex: regex, reason: merged[pattern]})
+	}
+
+	return matchers
+}
+
+// retryReason returns the reason of the first retryable error matching the output or error.
+func retryReason(matchers []retryableError, output string, err error) (string, bool) {
+	text := output + "\n" + err.Error()
+	for _, matcher := range matchers {
+		if matcher.regex.MatchString(text) {
+			return matcher.reason, true
+		}
+	}
+
+	return "", false
+}
+
+// runWithRetry runs fn until it succeeds, fails with an error that is not retryable or
+// config.Retry.MaxAttempts is reached.
+func runWithRetry(t *testing.T, config core.RunTime, action string, fn func() (string, error)) (string, error) {
+	attempts := max(config.Retry.MaxAttempts, 1)
+	backoff := config.Retry.Backoff
+
+	var matchers []retryableError
+	for attempt := 1; ; attempt++ {
+		output, err := fn()
+		var timeout *PhaseTimeoutError
+		if err == nil || attempt >= attempts || errors.As(err, &timeout) {
+
+			return output, err
+		}
+
+		if matchers == nil {
+			matchers = retryableErrors(config.Retry)
+		}
+		reason, ok := retryReason(matchers, output, err)
+		if !ok {
+
+			return output, err
+		}
+
+		logger.Log(t, fmt.Sprintf("TerraGrunt %s attempt %d/%d failed: %s, retrying in %s", action, attempt, attempts, reason, backoff))
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
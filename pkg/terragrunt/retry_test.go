@@ -0,0 +1,108 @@
+package terragrunt_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/terragrunt"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func retryConfig() core.RunTime {
+	return core.RunTime{Retry: core.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}}
+}
+
+func TestMockTgApply_RetriesEventualConsistency(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	mockExecutor.On("TgApplyAllE", t, mock.AnythingOfType("*terraform.Options")).
+		Return("Error: creating EC2 Instance: InvalidParameterValue: Value (profile) for parameter iamInstanceProfile.name is invalid",
+			fmt.Errorf("exit status 1")).Once()
+	mockExecutor.On("TgApplyAllE", t, mock.AnythingOfType("*terraform.Options")).Return("Mocked output", nil).Once()
+
+	err := terragrunt.Apply(t, &terraform.Options{}, mockExecutor, retryConfig(), cmdMockExecutor)
+
+	require.NoError(t, err)
+	mockExecutor.AssertNumberOfCalls(t, "TgApplyAllE", 2)
+}
+
+func TestMockTgDestroy_RetriesDependencyViolation(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	mockExecutor.On("TgDestroyAllE", t, mock.AnythingOfType("*terraform.Options")).
+		Return("Error: deleting Security Group: DependencyViolation: resource sg-123 has a dependent object",
+			fmt.Errorf("exit status 1"))
+
+	err := terragrunt.Destroy(t, &terraform.Options{}, mockExecutor, retryConfig(), cmdMockExecutor, false)
+
+	require.Error(t, err)
+	mockExecutor.AssertNumberOfCalls(t, "TgDestroyAllE", 3)
+}
+
+func TestMockTgApply_NotRetryable(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	mockExecutor.On("TgApplyAllE", t, mock.AnythingOfType("*terraform.Options")).
+		Return("Error: Unsupported argument", fmt.Errorf("exit status 1"))
+
+	err := terragrunt.Apply(t, &terraform.Options{}, mockExecutor, retryConfig(), cmdMockExecutor)
+
+	require.Error(t, err)
+	mockExecutor.AssertNumberOfCalls(t, "TgApplyAllE", 1)
+}
+
+func TestMockTgApply_NoSuchEntity(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		output string
+		calls  int
+	}{
+		"role not propagated": {"Error: attaching policy: NoSuchEntity: The role with name app-role cannot be found.", 2},
+		"missing policy":      {"Error: attaching policy: NoSuchEntity: Policy arn:aws:iam::123456789012:policy/app does not exist or is not attachable.", 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			mockExecutor := new(MockTerragruntExecutor)
+			cmdMockExecutor := new(MockCommandExecutor)
+
+			mockExecutor.On("TgApplyAllE", t, mock.AnythingOfType("*terraform.Options")).
+				Return(tc.output, fmt.Errorf("exit status 1")).Once()
+			mockExecutor.On("TgApplyAllE", t, mock.AnythingOfType("*terraform.Options")).Return("Mocked output", nil).Once()
+
+			_ = terragrunt.Apply(t, &terraform.Options{}, mockExecutor, retryConfig(), cmdMockExecutor)
+
+			mockExecutor.AssertNumberOfCalls(t, "TgApplyAllE", tc.calls)
+		})
+	}
+}
+
+func TestMockTgApply_CustomRetryableError(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	config := retryConfig()
+	core.WithRetryableError("(?s).*Throttling: Rate exceeded.*", "API throttled")(&config)
+
+	mockExecutor.On("TgApplyAllE", t, mock.AnythingOfType("*terraform.Options")).
+		Return("Error: Throttling: Rate exceeded", fmt.Errorf("exit status 1")).Once()
+	mockExecutor.On("TgApplyAllE", t, mock.AnythingOfType("*terraform.Options")).Return("Mocked output", nil).Once()
+
+	err := terragrunt.Apply(t, &terraform.Options{}, mockExecutor, config, cmdMockExecutor)
+
+	require.NoError(t, err)
+	assert.Equal(t, "API throttled", config.Retry.RetryableErrors["(?s).*Throttling: Rate exceeded.*"])
+	mockExecutor.AssertNumberOfCalls(t, "TgApplyAllE", 2)
+}
@@ -0,0 +1,28 @@
+package terragrunt
+
+import (
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// StackRunner is a stack.Runner going through Apply and Destroy for every module, so ordered runs get the
+// account guard, cache lock, plugin cache init, retries and vars restore of a run-all. Each module runs
+// terragrunt run-all in its own dir, which leaves external dependencies alone in non-interactive mode.
+// Destroy does not remove ENIs, that is left to the Destroy of the whole stack.
+type StackRunner struct {
+	Executor    Executor
+	Config      core.RunTime
+	CmdExecutor CommandExecutor
+}
+
+// ApplyE returns no output, Apply puts it in the error.
+func (r StackRunner) ApplyE(t *testing.T, options *terraform.Options) (string, error) {
+	return "", Apply(t, options, r.Executor, r.Config, r.CmdExecutor)
+}
+
+// DestroyE returns no output, Destroy puts it in the error.
+func (r StackRunner) DestroyE(t *testing.T, options *terraform.Options) (string, error) {
+	return "", Destroy(t, options, r.Executor, r.Config, r.CmdExecutor, false)
+}
@@ -0,0 +1,40 @@
+package terragrunt_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/stack"
+	"github.com/GoGstickGo/terratest-helpers/pkg/terragrunt"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func inDir(dir string) any {
+	return mock.MatchedBy(func(options *terraform.Options) bool { return options.TerraformDir == dir })
+}
+
+func TestMockStackRunner(t *testing.T) {
+	t.Parallel()
+	root := writeStack(t, "app/iam", "app/iam2")
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	graph, err := stack.Discover(root)
+	require.NoError(t, err)
+
+	mockExecutor.On("TgApplyAllE", t, inDir(filepath.Join(root, "app/iam"))).Return("", nil).Once()
+	mockExecutor.On("TgApplyAllE", t, inDir(filepath.Join(root, "app/iam2"))).Return("", fmt.Errorf("Mocked error")).Once()
+
+	runner := terragrunt.StackRunner{Executor: mockExecutor, Config: core.RunTime{RunID: "3f9a1c"}, CmdExecutor: cmdMockExecutor}
+	results, err := stack.Apply(t, graph, &terraform.Options{TerraformBinary: "terragrunt"}, runner)
+
+	require.Error(t, err)
+	assert.Equal(t, []string{"app/iam2"}, results.Failed())
+	assert.Contains(t, err.Error(), "Mocked error")
+	mockExecutor.AssertExpectations(t)
+}
@@ -0,0 +1,105 @@
+package terragrunt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/pkg/testutils"
+)
+
+// logPrefixRegex matches the module terragrunt adds to its own log lines, prefix=[/path/to/module].
+var logPrefixRegex = regexp.MustCompile(`prefix=\[([^\]]+)\]`)
+
+type streamKey struct{}
+
+type streamTarget struct {
+	t      *testing.T
+	dir    string
+	phase  string
+	logger testutils.Logger
+}
+
+// WithLogStream makes RealCommandExecutor.RunCommandContext log every output line to t as it is written,
+// the full output is still returned. Lines are labelled [module] [phase], the module is taken from the
+// prefix terragrunt adds with --terragrunt-include-module-prefix and is relative to dir.
+func WithLogStream(ctx context.Context, t *testing.T, dir, phase string) context.Context {
+	return context.WithValue(ctx, streamKey{}, streamTarget{t: t, dir: dir, phase: phase, logger: testutils.RealLogger{}})
+}
+
+// WithStreamLogger sends the lines streamed by WithLogStream to log instead of the terratest logger.
+func WithStreamLogger(ctx context.Context, log testutils.Logger) context.Context {
+	target, ok := streamFrom(ctx)
+	if !ok {
+		return ctx
+	}
+	target.logger = log
+
+	return context.WithValue(ctx, streamKey{}, target)
+}
+
+func streamFrom(ctx context.Context) (streamTarget, bool) {
+	target, ok := ctx.Value(streamKey{}).(streamTarget)
+
+	return target, ok
+}
+
+// lineLogger tees command output into buf and logs it line by line.
+type lineLogger struct {
+	target  streamTarget
+	buf     bytes.Buffer
+	partial []byte
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.buf.Write(p)
+
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		l.log(l.partial[:i])
+		l.partial = l.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush logs the last line when the output does not end with a newline.
+func (l *lineLogger) Flush() {
+	if len(l.partial) > 0 {
+		l.log(l.partial)
+		l.partial = nil
+	}
+}
+
+func (l *lineLogger) log(raw []byte) {
+	module, line := l.target.module(ansiRegex.ReplaceAllString(string(bytes.TrimRight(raw, "\r")), ""))
+	l.target.logger.Log(l.target.t, fmt.Sprintf("[%s] [%s] %s", module, l.target.phase, line))
+}
+
+// module returns the module line belongs to and line without its [module] prefix. Terraform output carries
+// a leading [module], terragrunt logs a prefix=[module] field, lines without either belong to RootModule.
+func (s streamTarget) module(line string) (string, string) {
+	module, rest := splitModulePrefix(line)
+	if module == RootModule {
+		if match := logPrefixRegex.FindStringSubmatch(line); match != nil {
+			module = match[1]
+		}
+	}
+
+	if filepath.IsAbs(module) && s.dir != "" {
+		if dir, err := filepath.Abs(s.dir); err == nil {
+			if rel, err := filepath.Rel(dir, module); err == nil {
+				module = rel
+			}
+		}
+	}
+
+	return module, rest
+}
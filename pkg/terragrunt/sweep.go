@@ -0,0 +1,32 @@
+package terragrunt
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/awsutils"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// DestroyAndSweep runs Destroy and then deletes the resources still tagged for this run, the sweep
+// also runs when Destroy fails so a failed test leaves as little behind as possible. It is skipped when
+// Destroy refused to run against the account, the credentials must not be used to delete anything then.
+func DestroyAndSweep(t *testing.T, options *terraform.Options, executor Executor, config core.RunTime, cmdExecutor CommandExecutor,
+	restore bool, sweep awsutils.SweepOptions, clients awsutils.SweepClients) (awsutils.SweepReport, error) {
+	destroyErr := Destroy(t, options, executor, config, cmdExecutor, restore)
+	if errors.Is(destroyErr, awsutils.ErrAccountNotAllowed) || errors.Is(destroyErr, awsutils.ErrAccountMismatch) {
+		logger.Log(t, "Sweep skipped, the account check failed")
+
+		return awsutils.SweepReport{}, destroyErr
+	}
+
+	report, err := awsutils.SweepTaggedResources(t, clients, sweep)
+	if err != nil {
+		err = fmt.Errorf("sweep failed: %w", err)
+	}
+
+	return report, errors.Join(destroyErr, err)
+}
@@ -1,16 +1,19 @@
 package terragrunt
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"strings"
 	"testing"
+	"time"
 
 	"github.com/GoGstickGo/terratest-helpers/core"
 	"github.com/GoGstickGo/terratest-helpers/pkg/awsutils"
-	"github.com/GoGstickGo/terratest-helpers/pkg/parameters"
 	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 )
 
@@ -18,9 +21,21 @@ import (
 type Executor interface {
 	TgApplyAllE(t *testing.T, options *terraform.Options) (string, error)
 	TgDestroyAllE(t *testing.T, options *terraform.Options) (string, error)
+	// TgPlanAllE runs run-all plan with -detailed-exitcode and returns the output and exit code.
+	TgPlanAllE(t *testing.T, options *terraform.Options) (string, int, error)
 	// Add other methods like TgInitAllE, TgDestroyAllE if needed.
 }
 
+// ContextExecutor is an Executor whose apply, destroy and plan can be cancelled. Apply, Destroy and Plan then
+// stop them at the deadline of their phase with a PhaseTimeoutError, other executors run until terragrunt is done.
+type ContextExecutor interface {
+	Executor
+	TgApplyAllContext(ctx context.Context, t *testing.T, options *terraform.Options) (string, error)
+	TgDestroyAllContext(ctx context.Context, t *testing.T, options *terraform.Options) (string, error)
+	// TgPlanAllContext works like TgPlanAllE, the command is interrupted when ctx is done.
+	TgPlanAllContext(ctx context.Context, t *testing.T, options *terraform.Options) (string, int, error)
+}
+
 type RealTerragruntExecutor struct{}
 
 func (e *RealTerragruntExecutor) TgApplyAllE(t *testing.T, options *terraform.Options) (string, error) {
@@ -31,120 +46,271 @@ func (e *RealTerragruntExecutor) TgDestroyAllE(t *testing.T, options *terraform.
 	return terraform.TgDestroyAllE(t, options)
 }
 
+// TgApplyAllContext works like TgApplyAllE with the output prefixed by module, the command is interrupted
+// when ctx is done.
+func (e *RealTerragruntExecutor) TgApplyAllContext(ctx context.Context, t *testing.T, options *terraform.Options) (string, error) {
+	output, _, err := runAllContext(ctx, t, options, nil, "apply", "-input=false", "-auto-approve", "--terragrunt-include-module-prefix")
+
+	return output, err
+}
+
+// TgDestroyAllContext works like TgDestroyAllE with the output prefixed by module, the command is interrupted
+// when ctx is done.
+func (e *RealTerragruntExecutor) TgDestroyAllContext(ctx context.Context, t *testing.T, options *terraform.Options) (string, error) {
+	output, _, err := runAllContext(ctx, t, options, nil, "destroy", "-auto-approve", "-input=false", "--terragrunt-include-module-prefix")
+
+	return output, err
+}
+
+// TgPlanAllContext works like TgPlanAllE, the command is interrupted when ctx is done.
+func (e *RealTerragruntExecutor) TgPlanAllContext(ctx context.Context, t *testing.T, options *terraform.Options) (string, int, error) {
+	changesPresent := func(exitCode int) bool { return exitCode == terraform.TerraformPlanChangesPresentExitCode }
+
+	return runAllContext(ctx, t, options, changesPresent, "plan", "--input=false", "--lock=true", "--detailed-exitcode",
+		"--terragrunt-include-module-prefix")
+}
+
+// runAllContext runs terragrunt run-all like terraform.RunTerraformCommandE, with its retries, through the
+// CommandExecutor passed to Apply, Destroy or Plan, so ctx stops the whole process group with its KillGrace. Exit codes accepted by success are not an error,
+// the exit code of the last run is returned.
+func runAllContext(ctx context.Context, t *testing.T, options *terraform.Options, success func(exitCode int) bool,
+	args ...string) (string, int, error) {
+	if options.TerraformBinary != "terragrunt" {
+		return "", terraform.DefaultErrorExitCode, terraform.TgInvalidBinary(options.TerraformBinary)
+	}
+
+	opts, args := terraform.GetCommonOptions(options, terraform.FormatArgs(options, append([]string{"run-all"}, args...)...)...)
+	description := fmt.Sprintf("%s %v", opts.TerraformBinary, args)
+	executor := commandExecutorFrom(ctx)
+
+	exitCode := terraform.DefaultSuccessExitCode
+	output, err := retry.DoWithRetryableErrorsE(t, description, opts.RetryableTerraformErrors, opts.MaxRetries, opts.TimeBetweenRetries, func() (string, error) {
+		output, err := runCommandContext(ctx, executor, opts.TerraformBinary, args, opts.TerraformDir, opts.EnvVars)
+		exitCode = exitCodeOf(err)
+		if err != nil && success != nil && success(exitCode) {
+			return string(output), nil
+		}
+
+		return string(output), err
+	})
+
+	return output, exitCode, err
+}
+
+// exitCodeOf returns the exit code of a command run with os/exec, errors without one count as a failure.
+func exitCodeOf(err error) int {
+	if err == nil {
+		return terraform.DefaultSuccessExitCode
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+
+	return terraform.DefaultErrorExitCode
+}
+
+// TgPlanAllE prefixes the output with the module path so it can be attributed per module.
+// Exit code 2 means changes are present and is not an error.
+func (e *RealTerragruntExecutor) TgPlanAllE(t *testing.T, options *terraform.Options) (string, int, error) {
+	if options.TerraformBinary != "terragrunt" {
+		return "", terraform.DefaultErrorExitCode, terraform.TgInvalidBinary(options.TerraformBinary)
+	}
+
+	opts, args := terraform.GetCommonOptions(options, terraform.FormatArgs(options, "run-all", "plan", "--input=false",
+		"--lock=true", "--detailed-exitcode", "--terragrunt-include-module-prefix")...)
+	cmd := shell.Command{
+		Command:    opts.TerraformBinary,
+		Args:       args,
+		WorkingDir: opts.TerraformDir,
+		Env:        opts.EnvVars,
+		Logger:     opts.Logger,
+	}
+
+	output, err := shell.RunCommandAndGetOutputE(t, cmd)
+	if err == nil {
+		return output, terraform.DefaultSuccessExitCode, nil
+	}
+
+	exitCode, exitErr := shell.GetExitCodeForRunCommandError(err)
+	if exitErr != nil {
+		return output, terraform.DefaultErrorExitCode, exitErr
+	}
+	if exitCode == terraform.TerraformPlanChangesPresentExitCode {
+		return output, exitCode, nil
+	}
+
+	return output, exitCode, err
+}
+
 // CommandExecutor abstracts command execution.
 type CommandExecutor interface {
 	RunCommand(cmdName string, args []string, dir string, envVars map[string]string) ([]byte, error)
 }
 
 // RealCommandExecutor executes real system commands.
-type RealCommandExecutor struct{}
+type RealCommandExecutor struct {
+	// KillGrace is how long a cancelled command gets to exit after SIGINT before its process group is killed.
+	KillGrace time.Duration
+}
 
 func (e *RealCommandExecutor) RunCommand(cmdName string, args []string, dir string, envVars map[string]string) ([]byte, error) {
-	cmd := exec.Command(cmdName, args...)
-	cmd.Dir = dir
-
-	// Prepare environment variables.
-	cmd.Env = os.Environ() // Start with existing environment variables.
-	for key, value := range envVars {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
-	}
+	return e.RunCommandContext(context.Background(), cmdName, args, dir, envVars)
+}
 
-	output, err := cmd.CombinedOutput()
+func tGiNit(t *testing.T, terra *terraform.Options, config core.RunTime, executor CommandExecutor, phase string) error {
+	_, err := runInit(t, terra, config, executor, phase)
 
-	return output, err
+	return err
 }
 
-func tGiNit(t *testing.T, terra *terraform.Options, config core.RunTime, executor CommandExecutor) error {
-	logger.Log(t, "TerraGrunt init in progress")
-
-	// Collect environment variables
-	envVars := map[string]string{
-		"TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE": "true",
-		"TERRAGRUNT_DOWNLOAD":                            config.Paths.TgDownloadDir,
-		"TF_PLUGIN_CACHE_DIR":                            config.Paths.TfPluginDir,
-		"TERRAGRUNT_NO_AUTO_INIT":                        "true",
+// terragruntEnv returns the variables passed to terragrunt commands run through a CommandExecutor.
+func terragruntEnv(config core.RunTime, pluginCache bool) map[string]string {
+	envVars := config.RunEnv()
+	if pluginCache {
+		envVars["TF_PLUGIN_CACHE_MAY_BREAK_DEPENDENCY_LOCK_FILE"] = "true"
+		envVars["TERRAGRUNT_DOWNLOAD"] = config.Paths.TgDownloadDir
+		envVars["TF_PLUGIN_CACHE_DIR"] = config.Paths.TfPluginDir
+		envVars["TERRAGRUNT_NO_AUTO_INIT"] = "true"
 	}
 	if config.IsDebug {
-		envVars["TERRAGRUNT_LOG_LEVEL"] = "debug"
-		envVars["TERRAGRUNT_DEBUG"] = ""
-		envVars["TF_LOG"] = "DEBUG"
+		for key, value := range debugEnv {
+			envVars[key] = value
+		}
 	}
 
-	// Command and arguments.
-	cmdName := "terragrunt"
-	args := []string{"run-all", "init", "--terragrunt-non-interactive"}
+	return envVars
+}
 
-	// Run the command
-	output, err := executor.RunCommand(cmdName, args, terra.TerraformDir, envVars)
-	if config.IsDebug {
-		logger.Log(t, "init output: %s\n", string(output))
+// debugEnv enables terragrunt and terraform debug logging.
+var debugEnv = map[string]string{
+	"TERRAGRUNT_LOG_LEVEL": "debug",
+	"TERRAGRUNT_DEBUG":     "",
+	"TF_LOG":               "DEBUG",
+}
+
+// setRunEnv exposes the run ID to the terragrunt commands run with options, later terraform.Output calls
+// with the same options see the same inputs.
+func setRunEnv(options *terraform.Options, config core.RunTime) {
+	if options.EnvVars == nil {
+		options.EnvVars = map[string]string{}
+	}
+	for key, value := range config.RunEnv() {
+		options.EnvVars[key] = value
 	}
+}
 
-	// Check for misconfigured plugin-cache.
-	checkStr := []string{"cache", "previously"}
-	for _, subStr := range checkStr {
-		if !strings.ContainsAny(string(output), subStr) {
-			if err := core.ClearFolder(t, config, core.OsFileSystem{}); err != nil {
+// guardAccount fails when config has allowed accounts and the AWS credentials are for another account.
+// It checks the credentials terragrunt gets through options.EnvVars and the process environment, not the
+// role the AWS helpers assume.
+func guardAccount(t *testing.T, options *terraform.Options, config core.RunTime) error {
+	if len(config.AllowedAccountIDs) == 0 {
 
-				return fmt.Errorf("clearing chache folder failed: %w", err)
-			}
-			if err := core.RestoreVarsFile(t, config, core.OsFileSystem{}); err != nil {
+		return nil
+	}
 
-				return fmt.Errorf("restore vars file failed: %w", err)
-			}
+	target, err := awsutils.TargetFromConfig(config, core.OsFileSystem{})
+	if err != nil {
 
-			return fmt.Errorf("\nplugin cache out of order:\n %s", string(output))
-		}
+		return fmt.Errorf("account guard: %w", err)
 	}
+	loader := &awsutils.EnvAWSConfigLoader{Env: options.EnvVars}
+	awsConfig, err := loader.LoadConfig(context.TODO(), target.Region)
+	if err != nil {
 
+		return fmt.Errorf("account guard: %w", err)
+	}
+	guard, err := awsutils.NewAccountGuard(config, awsConfig)
 	if err != nil {
-		if err := core.ClearFolder(t, config, core.OsFileSystem{}); err != nil {
 
-			return fmt.Errorf("clearing chache folder failed: %w", err)
-		}
-		if err := core.RestoreVarsFile(t, config, core.OsFileSystem{}); err != nil {
+		return fmt.Errorf("account guard: %w", err)
+	}
+	if err := guard.Check(t); err != nil {
 
-			return fmt.Errorf("restore vars file failed: %w", err)
+		return fmt.Errorf("account guard: %w", err)
+	}
+
+	return nil
+}
+
+// setDebugEnv enables terragrunt and terraform debug logging, tGiNit passes its own variables.
+func setDebugEnv(config core.RunTime) {
+	if config.IsDebug && !config.IsPluginCache {
+		for key, value := range debugEnv {
+			os.Setenv(key, value)
 		}
+	}
+}
 
-		return fmt.Errorf("%w\nOutput:\n%s", err, output)
+// lockCache serializes access to the shared terragrunt cache when locking is enabled.
+func lockCache(t *testing.T, config core.RunTime) (func(), error) {
+	if !config.IsLocking {
+		return func() {}, nil
 	}
-	logger.Log(t, "terragrunt init completed")
 
-	return nil
+	lock, err := core.AcquireLock(t, config, core.CacheLockPath(config))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock terragrunt cache: %w", err)
+	}
+
+	return func() {
+		if err := lock.Release(); err != nil {
+			logger.Log(t, "Failed to release terragrunt cache lock:", err)
+		}
+	}, nil
 }
 
 func Apply(t *testing.T, options *terraform.Options, executor Executor, config core.RunTime, cmdExecutor CommandExecutor) error {
+	if err := guardAccount(t, options, config); err != nil {
+
+		return err
+	}
+
+	unlock, err := lockCache(t, config)
+	if err != nil {
+
+		return err
+	}
+	defer unlock()
+	// The vars file is not restored ahead of the deadline while terragrunt reads it.
+	defer core.UseVarsFile(config)()
+
+	setRunEnv(options, config)
 
 	if config.IsPluginCache {
-		if err := tGiNit(t, options, config, cmdExecutor); err != nil {
+		if err := tGiNit(t, options, config, cmdExecutor, PhaseInit); err != nil {
 
 			return fmt.Errorf("terragrunt init failed: %w", err)
 		}
 	}
 
-	if config.IsDebug && !config.IsPluginCache {
-		os.Setenv("TERRAGRUNT_LOG_LEVEL", "debug")
-		os.Setenv("TERRAGRUNT_DEBUG", "")
-		os.Setenv("TF_LOG", "DEBUG")
-	}
+	setDebugEnv(config)
 
 	logger.Log(t, "TerraGrunt Apply in progress")
-	output, err := executor.TgApplyAllE(t, options)
+	output, err := runWithRetry(t, config, "apply", func() (string, error) {
+		ctxExecutor, ok := executor.(ContextExecutor)
+		if !ok {
+			return executor.TgApplyAllE(t, options)
+		}
+
+		return runPhase(t, config, PhaseApply, options.TerraformDir, cmdExecutor, func(ctx context.Context) (string, error) {
+			return ctxExecutor.TgApplyAllContext(ctx, t, options)
+		})
+	})
 	if err != nil {
+		errs := []error{fmt.Errorf("failed to apply Terragrunt ,output: %s, error: %w", output, err)}
 		if config.IsPluginCache {
 			// Remove cached files.
 			if err := core.ClearFolder(t, config, core.OsFileSystem{}); err != nil {
-
-				return fmt.Errorf("error clearing cache folder: %w", err)
+				errs = append(errs, fmt.Errorf("error clearing cache folder: %w", err))
 			}
 		}
 		if err := core.RestoreVarsFile(t, config, core.OsFileSystem{}); err != nil {
-
-			return fmt.Errorf("restore vars file failed: %w", err)
+			errs = append(errs, fmt.Errorf("restore vars file failed: %w", err))
 		}
 
-		return fmt.Errorf("failed to apply Terragrunt ,output: %s, error: %w", output, err)
+		return errors.Join(errs...)
 	}
 
 	return nil
@@ -153,22 +319,48 @@ func Apply(t *testing.T, options *terraform.Options, executor Executor, config c
 func Destroy(t *testing.T, options *terraform.Options, executor Executor, config core.RunTime, cmdExecutor CommandExecutor, restore bool) error {
 	logger.Log(t, "Defer func started")
 
+	if err := guardAccount(t, options, config); err != nil {
+
+		return err
+	}
+
+	unlock, err := lockCache(t, config)
+	if err != nil {
+
+		return err
+	}
+	defer unlock()
+	// The vars file is not restored ahead of the deadline while terragrunt reads it.
+	defer core.UseVarsFile(config)()
+
+	setRunEnv(options, config)
+
 	if config.IsPluginCache {
-		if err := tGiNit(t, options, config, cmdExecutor); err != nil {
+		if err := tGiNit(t, options, config, cmdExecutor, PhaseDestroyInit); err != nil {
 
 			return fmt.Errorf("terragrunt init failed: %w", err)
 		}
 	}
 
 	logger.Log(t, "TerraGrunt destroy in progress")
-	stdout, err := executor.TgDestroyAllE(t, options)
+	stdout, err := runWithRetry(t, config, "destroy", func() (string, error) {
+		ctxExecutor, ok := executor.(ContextExecutor)
+		if !ok {
+			return executor.TgDestroyAllE(t, options)
+		}
+
+		return runPhase(t, config, PhaseDestroy, options.TerraformDir, cmdExecutor, func(ctx context.Context) (string, error) {
+			return ctxExecutor.TgDestroyAllContext(ctx, t, options)
+		})
+	})
 	if err != nil {
-		if err := core.RestoreVarsFile(t, config, core.OsFileSystem{}); err != nil {
+		err = fmt.Errorf("failed to destroy with Terragrunt ,output: %s, error: %w", stdout, err)
+		if restoreErr := core.RestoreVarsFile(t, config, core.OsFileSystem{}); restoreErr != nil {
 
-			return fmt.Errorf("restore vars file failed: %w", err)
+			return errors.Join(err, fmt.Errorf("restore vars file failed: %w", restoreErr))
 		}
 
-		return fmt.Errorf("failed to destroy with Terragrunt ,output: %s, error: %w", stdout, err)
+		return err
 	}
 
 	if config.IsPluginCache {
@@ -179,28 +371,57 @@ func Destroy(t *testing.T, options *terraform.Options, executor Executor, config
 		}
 	}
 
-	var errs []error
+	if restore {
+
+		return restoreAfterDestroy(t, config)
+	}
 
-	ec2Client, err := awsutils.LoadEC2Client(parameters.AWSRegion)
+	return nil
+}
+
+// restoreAfterDestroy restores the vars file and removes the ENIs left in the VPC of the stack.
+func restoreAfterDestroy(t *testing.T, config core.RunTime) error {
+	// Read the account and region before root_vars.hcl gets its original content back.
+	factory, err := awsutils.NewClientFactoryFromConfig(config)
 	if err != nil {
 
-		return fmt.Errorf("error loading EC2 client: %w", err)
+		return fmt.Errorf("error creating AWS client factory: %w", err)
 	}
 
-	if restore {
-		// Restore the original content of root_vars.hcl.
-		if err = core.RestoreVarsFile(t, config, core.OsFileSystem{}); err != nil {
-			errs = append(errs, fmt.Errorf("error restoring %s: %w", config.VarsFile, err))
-		}
-		if _, err := awsutils.RemoveENI(t, parameters.VPCId, ec2Client); err != nil {
-			errs = append(errs, fmt.Errorf("error deleting AWS EC2 ENIs: %w", err))
-		}
+	var errs []error
+
+	// Restore the original content of root_vars.hcl.
+	if err = core.RestoreVarsFile(t, config, core.OsFileSystem{}); err != nil {
+		errs = append(errs, fmt.Errorf("error restoring %s: %w", config.VarsFile, err))
+	}
+	if err := removeENIs(t, factory); err != nil {
+		errs = append(errs, err)
 	}
 	if len(errs) > 0 {
 
 		return fmt.Errorf("restore failed: %v", errs)
 	}
 
+	return nil
+}
+
+// removeENIs deletes the available ENIs of the VPC of the stack with the verified clients of factory.
+func removeENIs(t *testing.T, factory *awsutils.ClientFactory) error {
+	ec2Client, err := factory.EC2("")
+	if err != nil {
+
+		return fmt.Errorf("error loading EC2 client: %w", err)
+	}
+	guard, err := factory.Guard()
+	if err != nil {
+
+		return fmt.Errorf("error loading account guard: %w", err)
+	}
+	if _, err := awsutils.RemoveENI(t, guard, factory.Target.VPCID, ec2Client); err != nil {
+
+		return fmt.Errorf("error deleting AWS EC2 ENIs: %w", err)
+	}
+
 	return nil
 } /*func UpdateTerraformHook(dir, key, newLine string) error {
 	log.Print("Update terraform_hook")
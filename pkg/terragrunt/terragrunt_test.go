@@ -1,14 +1,17 @@
 package terragrunt_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/GoGstickGo/terratest-helpers/core"
+	"github.com/GoGstickGo/terratest-helpers/pkg/awsutils"
 	"github.com/GoGstickGo/terratest-helpers/pkg/parameters"
 	"github.com/GoGstickGo/terratest-helpers/pkg/terragrunt"
 	"github.com/GoGstickGo/terratest-helpers/pkg/testutils"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -31,6 +34,12 @@ func (m *MockTerragruntExecutor) TgDestroyAllE(t *testing.T, options *terraform.
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockTerragruntExecutor) TgPlanAllE(t *testing.T, options *terraform.Options) (string, int, error) {
+	args := m.Called(t, options)
+
+	return args.String(0), args.Int(1), args.Error(2)
+}
+
 type MockCommandExecutor struct {
 	mock.Mock
 }
@@ -50,6 +59,17 @@ func (m *MockLogger) Log(t *testing.T, args ...interface{}) {
 	m.Called(combinedArgs...)
 }
 
+type MockTaggingClient struct {
+	mock.Mock
+}
+
+func (m *MockTaggingClient) GetResources(ctx context.Context, params *resourcegroupstaggingapi.GetResourcesInput,
+	_ ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+	args := m.Called(ctx, params)
+
+	return args.Get(0).(*resourcegroupstaggingapi.GetResourcesOutput), args.Error(1)
+}
+
 type MockSleeper struct {
 	mock.Mock
 }
@@ -58,6 +78,12 @@ func (m *MockSleeper) Sleep(duration time.Duration) {
 	m.Called(duration)
 }
 
+func (m *MockSleeper) SleepUntil(duration time.Duration, done <-chan struct{}) bool {
+	args := m.Called(duration, done)
+
+	return args.Bool(0)
+}
+
 func TestMockTgApply_Success(t *testing.T) {
 	t.Parallel()
 	// Create a mock executors
@@ -84,6 +110,56 @@ func TestMockTgApply_Success(t *testing.T) {
 	mockExecutor.AssertExpectations(t)
 }
 
+func TestMockTgApply_RunID(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	mockExecutor.On("TgApplyAllE", t, mock.MatchedBy(func(options *terraform.Options) bool {
+		return options.EnvVars["TT_RUN_ID"] == "3f9a1c" && options.EnvVars["TF_VAR_tt_run_id"] == "3f9a1c"
+	})).Return("Mocked output", nil)
+
+	options := &terraform.Options{EnvVars: map[string]string{"AWS_PROFILE": "test"}}
+	config := core.RunTime{RunID: "3f9a1c"}
+
+	err := terragrunt.Apply(t, options, mockExecutor, config, cmdMockExecutor)
+
+	require.NoError(t, err)
+	assert.Equal(t, "test", options.EnvVars["AWS_PROFILE"])
+	assert.Equal(t, "3f9a1c", options.EnvVars["TT_RUN_ID"], "terraform.Output with the same options sees the run ID")
+	mockExecutor.AssertExpectations(t)
+}
+
+func TestMockTgApply_AccountNotAllowed(t *testing.T) {
+	t.Parallel()
+	mockExecutor := new(MockTerragruntExecutor)
+	cmdMockExecutor := new(MockCommandExecutor)
+
+	config := core.RunTime{
+		Paths:             core.FolderPaths{TerragruntDir: t.TempDir()},
+		VarsFile:          "root_vars.hcl",
+		Content:           parameters.TGRootVars,
+		AllowedAccountIDs: []string{"222222222222"},
+	}
+
+	err := terragrunt.Apply(t, &terraform.Options{}, mockExecutor, config, cmdMockExecutor)
+	require.ErrorIs(t, err, awsutils.ErrAccountNotAllowed)
+
+	err = terragrunt.Destroy(t, &terraform.Options{}, mockExecutor, config, cmdMockExecutor, false)
+	require.ErrorIs(t, err, awsutils.ErrAccountNotAllowed)
+
+	// The sweep must not run with credentials the guard refused.
+	tagging := &MockTaggingClient{}
+	report, err := terragrunt.DestroyAndSweep(t, &terraform.Options{}, mockExecutor, config, cmdMockExecutor, false,
+		awsutils.SweepOptions{RunTagKey: "tt_run_id", RunTagValue: "run-1"}, awsutils.SweepClients{Tagging: tagging, IAMTagging: tagging})
+	require.ErrorIs(t, err, awsutils.ErrAccountNotAllowed)
+	assert.Empty(t, report.Deleted)
+	tagging.AssertNotCalled(t, "GetResources", mock.Anything, mock.Anything)
+
+	mockExecutor.AssertNotCalled(t, "TgApplyAllE", mock.Anything, mock.Anything)
+	mockExecutor.AssertNotCalled(t, "TgDestroyAllE", mock.Anything, mock.Anything)
+}
+
 func TestMockTgDestroy_Success(t *testing.T) {
 	t.Parallel()
 	// Create a mock executors
@@ -131,7 +207,8 @@ func TestMockTgDestroy_Failure(t *testing.T) {
 
 	// Assertions
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "restore vars file failed")
+	assert.Contains(t, err.Error(), "Mocked error")
+	assert.NotContains(t, err.Error(), "restore vars file failed")
 	mockExecutor.AssertExpectations(t)
 }
 
@@ -159,7 +236,8 @@ func TestMockTgApply_Failure(t *testing.T) {
 
 	// Assertions
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "restore vars file failed")
+	assert.Contains(t, err.Error(), "Mocked error")
+	assert.NotContains(t, err.Error(), "restore vars file failed")
 	mockExecutor.AssertExpectations(t)
 }
 
@@ -169,18 +247,19 @@ func TestTerragrunt(t *testing.T) {
 	t.Setenv("TT_TERRAGRUNT_ROOT_DIR", "../../example")
 	t.Setenv("TT_PAUSE", "2")
 	config := core.NewConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
 
-	originalContent, err := core.UpdateVarsFile(t, config, core.OsFileSystem{})
-	if err != nil {
+	if _, err := core.SetupVarsFile(t, config, core.OsFileSystem{}, config.IsPluginCache); err != nil {
 		t.Fatalf("failed to update %s file, err:%v", config.VarsFile, err)
 	}
-	config.Content = string(originalContent)
 
 	iamOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../example/app/iam",
 		TerraformBinary: "terragrunt",
 		Vars: map[string]interface{}{
-			"iam_policy_name": "TestDummy-" + parameters.AWSRegion,
+			"iam_policy_name": config.UniqueName("TestDummy-" + parameters.AWSRegion),
 		},
 	})
 
@@ -202,7 +281,8 @@ func TestTerragrunt(t *testing.T) {
 
 	// IAM policy test cases
 	policyArn := terraform.Output(t, iamOptions, "policy_arn")
-	assert.Equal(t, policyArn, "arn:aws:iam::"+parameters.AWSAccountID+":policy/TestDummy-us-east-1", "Policy arn should match arn:aws:iam::"+parameters.AWSAccountID+":policy/TestDummy-us-east-1")
+	wantArn := "arn:aws:iam::" + parameters.AWSAccountID + ":policy/" + config.UniqueName("TestDummy-us-east-1")
+	assert.Equal(t, policyArn, wantArn, "Policy arn should match "+wantArn)
 
 	iam2Options := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
 		TerraformDir:    "../../example/app/iam2",
@@ -221,7 +301,8 @@ func TestTerragrunt(t *testing.T) {
 
 	// IAM policy test cases
 	policy2Arn := terraform.Output(t, iam2Options, "policy_arn")
-	assert.Equal(t, policy2Arn, "arn:aws:iam::"+parameters.AWSAccountID+":policy/DummyTest2-us-east-1", "Policy arn should match arn:aws:iam::"+parameters.AWSAccountID+":policy/DummyTest-us-east-1")
+	want2Arn := "arn:aws:iam::" + parameters.AWSAccountID + ":policy/" + config.UniqueName("DummyTest2-us-east-1")
+	assert.Equal(t, policy2Arn, want2Arn, "Policy arn should match "+want2Arn)
 
 	// Pause test
 	testutils.PauseTest(t, config, logger, sleeper)
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package testutils
+
+import "errors"
+
+var errPauseModeUnsupported = errors.New("not supported on this platform")
+
+func watchSignal(_ <-chan struct{}, _ func(string)) (string, error) {
+	return "", errPauseModeUnsupported
+}
+
+func watchKey(_ <-chan struct{}, _ func(string)) (string, error) {
+	return "", errPauseModeUnsupported
+}
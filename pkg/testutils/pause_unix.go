@@ -0,0 +1,51 @@
+//go:build unix
+
+package testutils
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchSignal wakes on SIGUSR1.
+func watchSignal(stop <-chan struct{}, wake func(string)) (string, error) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+
+	go func() {
+		defer signal.Stop(signals)
+
+		select {
+		case <-stop:
+		case <-signals:
+			wake("SIGUSR1")
+		}
+	}()
+
+	return fmt.Sprintf("kill -USR1 %d", os.Getpid()), nil
+}
+
+// watchKey wakes when a line is entered on the controlling terminal.
+func watchKey(stop <-chan struct{}, wake func(string)) (string, error) {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return "", err
+	}
+
+	go func() {
+		buf := make([]byte, 1)
+		if _, err := tty.Read(buf); err == nil {
+			wake("key press")
+		}
+	}()
+
+	// Closing the terminal unblocks the pending read.
+	go func() {
+		<-stop
+		tty.Close()
+	}()
+
+	return "press Enter", nil
+}
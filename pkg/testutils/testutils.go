@@ -1,6 +1,10 @@
 package testutils
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -8,6 +12,16 @@ import (
 	"github.com/gruntwork-io/terratest/modules/logger"
 )
 
+const (
+	PauseModeSleep  = "sleep"
+	PauseModeFile   = "file"
+	PauseModeSignal = "signal"
+	PauseModeKey    = "key"
+)
+
+// filePollInterval is how often the sentinel file is checked.
+const filePollInterval = time.Second
+
 type Logger interface {
 	Log(t *testing.T, args ...interface{})
 }
@@ -20,6 +34,8 @@ func (RealLogger) Log(t *testing.T, args ...interface{}) {
 
 type Sleeper interface {
 	Sleep(duration time.Duration)
+	// SleepUntil blocks until done is closed or duration elapses, it reports whether done was closed first.
+	SleepUntil(duration time.Duration, done <-chan struct{}) bool
 }
 
 type RealSleeper struct{}
@@ -27,7 +43,130 @@ type RealSleeper struct{}
 func (RealSleeper) Sleep(duration time.Duration) {
 	time.Sleep(duration)
 }
+
+func (RealSleeper) SleepUntil(duration time.Duration, done <-chan struct{}) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// PauseTest pauses the test before the environment is destroyed.
+// By default it sleeps for config.Pause. config.PauseMode can list, comma separated, events that end the pause
+// early with config.Pause as the upper bound: "file" waits until config.PauseFile is created or removed,
+// "signal" waits for SIGUSR1 and "key" waits for Enter on the TTY.
 func PauseTest(t *testing.T, config core.RunTime, logger Logger, sleeper Sleeper) {
-	logger.Log(t, "Pause test for", config.Pause, "before starting destruction of the environment")
-	sleeper.Sleep(config.Pause)
+	modes := pauseModes(config.PauseMode)
+	if len(modes) == 0 {
+		logger.Log(t, "Pause test for", config.Pause, "before starting destruction of the environment")
+		sleeper.Sleep(config.Pause)
+
+		return
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	done := make(chan struct{})
+	var once sync.Once
+	var reason string
+	wake := func(why string) {
+		once.Do(func() {
+			reason = why
+			close(done)
+		})
+	}
+
+	var hints []string
+	for _, mode := range modes {
+		switch mode {
+		case PauseModeFile:
+			path := pauseFile(config)
+			hints = append(hints, watchFile(path, stop, wake))
+		case PauseModeSignal:
+			hint, err := watchSignal(stop, wake)
+			if err != nil {
+				logger.Log(t, "Pause on signal unavailable:", err)
+
+				continue
+			}
+			hints = append(hints, hint)
+		case PauseModeKey:
+			hint, err := watchKey(stop, wake)
+			if err != nil {
+				logger.Log(t, "Pause on key press unavailable:", err)
+
+				continue
+			}
+			hints = append(hints, hint)
+		default:
+			logger.Log(t, "Unknown pause mode", mode)
+		}
+	}
+
+	logger.Log(t, "Pause test for up to", config.Pause, "before starting destruction of the environment, continue with:", strings.Join(hints, ", "))
+
+	if sleeper.SleepUntil(config.Pause, done) {
+		logger.Log(t, "Pause ended by", reason)
+
+		return
+	}
+	logger.Log(t, "Pause timed out after", config.Pause)
+}
+
+// pauseModes returns the wait modes, an empty result means a plain sleep.
+func pauseModes(value string) []string {
+	var modes []string
+	for _, mode := range strings.Split(value, ",") {
+		mode = strings.ToLower(strings.TrimSpace(mode))
+		if mode != "" && mode != PauseModeSleep {
+			modes = append(modes, mode)
+		}
+	}
+
+	return modes
+}
+
+func pauseFile(config core.RunTime) string {
+	if config.PauseFile != "" {
+		return config.PauseFile
+	}
+
+	return filepath.Join(os.TempDir(), "terratest-pause")
+}
+
+// watchFile wakes when path is created if it is missing, or removed if it exists.
+func watchFile(path string, stop <-chan struct{}, wake func(string)) string {
+	_, err := os.Stat(path)
+	existed := err == nil
+
+	go func() {
+		ticker := time.NewTicker(filePollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				_, err := os.Stat(path)
+				if exists := err == nil; exists != existed {
+					wake("sentinel file " + path)
+
+					return
+				}
+			}
+		}
+	}()
+
+	if existed {
+		return "rm " + path
+	}
+
+	return "touch " + path
 }
@@ -1,6 +1,8 @@
 package testutils_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -25,6 +27,13 @@ type MockSleeper struct {
 func (m *MockSleeper) Sleep(duration time.Duration) {
 	m.Called(duration)
 }
+
+func (m *MockSleeper) SleepUntil(duration time.Duration, done <-chan struct{}) bool {
+	args := m.Called(duration, done)
+
+	return args.Bool(0)
+}
+
 func TestMockPauseTest(t *testing.T) {
 	t.Parallel()
 	// Create mock logger
@@ -58,3 +67,90 @@ func TestMockPauseTest(t *testing.T) {
 	mockLogger.AssertExpectations(t)
 	mockSleeper.AssertExpectations(t)
 }
+
+func TestMockPauseTest_File(t *testing.T) {
+	t.Parallel()
+	// Create mock logger
+	mockLogger := new(MockLogger)
+
+	// Create mock sleeper
+	mockSleeper := new(MockSleeper)
+
+	// Prepare config
+	pauseDuration := time.Hour
+	sentinel := filepath.Join(t.TempDir(), "continue")
+	config := core.RunTime{
+		Pause:     pauseDuration,
+		PauseMode: testutils.PauseModeFile,
+		PauseFile: sentinel,
+	}
+
+	mockLogger.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockLogger.On("Log", t, "Pause ended by", "sentinel file "+sentinel).Return()
+
+	// The sleeper blocks until the watcher reports the sentinel file
+	mockSleeper.On("SleepUntil", pauseDuration, mock.Anything).Run(func(args mock.Arguments) {
+		if err := os.WriteFile(sentinel, nil, 0644); err != nil {
+			t.Errorf("failed to create sentinel file: %v", err)
+		}
+
+		select {
+		case <-args.Get(1).(<-chan struct{}):
+		case <-time.After(10 * time.Second):
+			t.Errorf("sentinel file was not detected")
+		}
+	}).Return(true)
+
+	// Call the function under test
+	testutils.PauseTest(t, config, mockLogger, mockSleeper)
+
+	// Assertions
+	mockLogger.AssertExpectations(t)
+	mockSleeper.AssertExpectations(t)
+}
+
+func TestMockPauseTest_Timeout(t *testing.T) {
+	t.Parallel()
+	// Create mock logger
+	mockLogger := new(MockLogger)
+
+	// Create mock sleeper
+	mockSleeper := new(MockSleeper)
+
+	// Prepare config
+	pauseDuration := 2 * time.Second
+	config := core.RunTime{
+		Pause:     pauseDuration,
+		PauseMode: "file, unknown",
+		PauseFile: filepath.Join(t.TempDir(), "continue"),
+	}
+
+	mockLogger.On("Log", t, "Unknown pause mode", "unknown").Return()
+	mockLogger.On("Log", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockLogger.On("Log", t, "Pause timed out after", pauseDuration).Return()
+
+	// Nothing happens before the upper bound
+	mockSleeper.On("SleepUntil", pauseDuration, mock.Anything).Return(false)
+
+	// Call the function under test
+	testutils.PauseTest(t, config, mockLogger, mockSleeper)
+
+	// Assertions
+	mockLogger.AssertExpectations(t)
+	mockSleeper.AssertExpectations(t)
+	mockSleeper.AssertNotCalled(t, "Sleep", mock.Anything)
+}
+
+func TestMockRealSleeperSleepUntil(t *testing.T) {
+	t.Parallel()
+
+	done := make(chan struct{})
+	close(done)
+	if !(testutils.RealSleeper{}).SleepUntil(time.Hour, done) {
+		t.Errorf("expected SleepUntil to return early")
+	}
+
+	if (testutils.RealSleeper{}).SleepUntil(time.Millisecond, make(chan struct{})) {
+		t.Errorf("expected SleepUntil to time out")
+	}
+}